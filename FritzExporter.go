@@ -9,6 +9,7 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/wbwue/FritzExporter/pkg/config"
 	"github.com/wbwue/FritzExporter/pkg/scraper"
@@ -35,6 +36,9 @@ var (
 
 func main() {
 	cfg := config.NewConfig()
+	var configFile string
+	targets := cli.NewStringSlice()
+	authModules := cli.NewStringSlice()
 	app := &cli.App{
 		Name:    "FritzExporter",
 		Version: fmt.Sprintf("%s (%s)", Version, Revision),
@@ -52,12 +56,29 @@ func main() {
 		Usage:   "Prints the current version",
 	}
 	app.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "config.file",
+			Usage:       "YAML file with targets, collectors and further settings, overrides the flags and is reloaded on SIGHUP or POST /-/reload",
+			EnvVars:     []string{"FRITZ_EXPORTER_CONFIG_FILE"},
+			Destination: &configFile,
+		},
 		&cli.StringFlag{
 			Name:        "fritzbox-url",
 			Usage:       "URL to connect to",
 			EnvVars:     []string{"FRITZ_FRITZBOX_URL"},
 			Destination: &cfg.FritzBoxURL,
-			Required:    true,
+		},
+		&cli.StringSliceFlag{
+			Name:        "target",
+			Usage:       "Box to scrape as [name=]url, repeat for several boxes, credentials can be given as user:password@ in the url",
+			EnvVars:     []string{"FRITZ_EXPORTER_TARGETS"},
+			Destination: targets,
+		},
+		&cli.StringSliceFlag{
+			Name:        "auth-module",
+			Usage:       "Credentials for /probe of boxes that are not configured as --target, as name=user:password",
+			EnvVars:     []string{"FRITZ_EXPORTER_AUTH_MODULES"},
+			Destination: authModules,
 		},
 		&cli.StringFlag{
 			Name:        "username",
@@ -77,7 +98,7 @@ func main() {
 			Name:        "password",
 			Usage:       "Password to login into Fritz!Box",
 			EnvVars:     []string{"FRITZ_PASSWORD"},
-			Required:    true,
+			Required:    false,
 			Destination: &cfg.Password,
 		},
 		&cli.StringFlag{
@@ -87,6 +108,13 @@ func main() {
 			EnvVars:     []string{"FRITZ_EXPORTER_METRICS_ADDRESS"},
 			Destination: &cfg.MetricsAddress,
 		},
+		&cli.DurationFlag{
+			Name:        "cache-ttl",
+			Value:       15 * time.Second,
+			Usage:       "How long data fetched from the box is served before it is fetched again",
+			EnvVars:     []string{"FRITZ_EXPORTER_CACHE_TTL"},
+			Destination: &cfg.CacheTTL,
+		},
 		&cli.StringFlag{
 			Name:        "fritz-log-path",
 			Value:       "",
@@ -101,10 +129,65 @@ func main() {
 			EnvVars:     []string{"FRITZ_EXPORTER_LOKI_ADDRESS"},
 			Destination: &cfg.LokiURL,
 		},
+		&cli.StringFlag{
+			Name:        "state-file",
+			Value:       "",
+			Usage:       "Where to keep the session id across restarts, if unset, every start logs in again",
+			EnvVars:     []string{"FRITZ_EXPORTER_STATE_FILE"},
+			Destination: &cfg.StateFile,
+		},
+		&cli.BoolFlag{
+			Name:        "filter-own-logins",
+			Value:       false,
+			Usage:       "Drop the successful logins and logouts of the exporter's user from its own address from the FritzBox log",
+			EnvVars:     []string{"FRITZ_EXPORTER_FILTER_OWN_LOGINS"},
+			Destination: &cfg.FilterOwnLogin,
+		},
+	}
+
+	for _, name := range scraper.CollectorNames() {
+		app.Flags = append(app.Flags,
+			&cli.BoolFlag{
+				Name:  "collector." + name,
+				Value: scraper.CollectorEnabledByDefault(name),
+				Usage: "Enable the " + name + " collector",
+			},
+			&cli.BoolFlag{
+				Name:  "no-collector." + name,
+				Usage: "Disable the " + name + " collector",
+			},
+			&cli.DurationFlag{
+				Name:  "collector." + name + ".interval",
+				Usage: "How long data of the " + name + " collector is cached, defaults to --cache-ttl",
+			},
+		)
 	}
 
 	app.Action = func(c *cli.Context) error {
-		return execute(cfg)
+		// every (re)load starts from the flags, so settings removed from the
+		// config file fall back to them
+		load := func() (*config.Config, error) {
+			loaded := *cfg
+			loaded.Collectors = make(map[string]config.CollectorConfig)
+			for _, name := range scraper.CollectorNames() {
+				loaded.Collectors[name] = config.CollectorConfig{
+					Enabled:  c.Bool("collector."+name) && !c.Bool("no-collector."+name),
+					Interval: c.Duration("collector." + name + ".interval"),
+				}
+			}
+			if configFile != "" {
+				f, err := config.LoadFile(configFile, scraper.CollectorNames())
+				if err != nil {
+					return nil, err
+				}
+				return &loaded, loaded.ApplyFile(f, targets.Value(), authModules.Value())
+			}
+			if err := loaded.ResolveTargets(targets.Value()); err != nil {
+				return nil, err
+			}
+			return &loaded, loaded.ResolveAuthModules(authModules.Value())
+		}
+		return execute(load)
 	}
 
 	if err := app.Run(os.Args); err != nil {
@@ -113,7 +196,11 @@ func main() {
 	}
 }
 
-func execute(cfg *config.Config) error {
+func execute(load func() (*config.Config, error)) error {
+	cfg, err := load()
+	if err != nil {
+		return err
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -128,12 +215,72 @@ func execute(cfg *config.Config) error {
 			"buildDate", BuildDate,
 			"goVersion", GoVersion,
 		)
+	}
+	manager := scraper.NewManager(log.With(setupLogging(cfg), "component", "fritz_exporter"))
+	manager.Apply(cfg)
+	prometheus.MustRegister(manager)
+	prober := scraper.NewProber(cfg, log.With(setupLogging(cfg), "component", "probe"))
+	{
+		logger := log.With(setupLogging(cfg), "component", "fritz_exporter")
+		g.Add(func() error {
+			return manager.Run(ctx)
+		}, func(_ error) {
+			level.Info(logger).Log("msg", "shutting down scrapers")
+		})
+		g.Add(func() error {
+			return prober.Run(ctx)
+		}, func(_ error) {
+		})
+	}
+
+	reloadRequests := make(chan chan error)
+	{
+		logger := log.With(setupLogging(cfg), "component", "reloader")
+		reloadSuccess := promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "fritz_exporter_config_last_reload_successful",
+			Help: "Whether the last configuration reload attempt was successful.",
+		})
+		reloadSeconds := promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "fritz_exporter_config_last_reload_success_timestamp_seconds",
+			Help: "Timestamp of the last successful configuration reload.",
+		})
+		reloadSuccess.Set(1)
+		reloadSeconds.SetToCurrentTime()
 
-		s := scraper.NewScraper(cfg, logger)
+		reload := func() error {
+			newCfg, err := load()
+			if err != nil {
+				reloadSuccess.Set(0)
+				level.Error(logger).Log("msg", "error reloading config, keeping the previous one", "err", err)
+				return err
+			}
+			if newCfg.MetricsAddress != cfg.MetricsAddress || newCfg.LogLevel != cfg.LogLevel {
+				level.Warn(logger).Log("msg", "changes of the listen address and log level need a restart")
+			}
+			manager.Apply(newCfg)
+			prober.SetConfig(newCfg)
+			reloadSuccess.Set(1)
+			reloadSeconds.SetToCurrentTime()
+			level.Info(logger).Log("msg", "reloaded config", "targets", len(newCfg.Targets))
+			return nil
+		}
+
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
 		g.Add(func() error {
-			return s.Run(ctx)
+			for {
+				select {
+				case <-hup:
+					reload()
+				case result := <-reloadRequests:
+					result <- reload()
+				case <-ctx.Done():
+					return nil
+				}
+			}
 		}, func(_ error) {
-			level.Info(logger).Log("msg", "shutting down socket server")
+			signal.Stop(hup)
+			cancel()
 		})
 	}
 	{
@@ -155,6 +302,25 @@ func execute(cfg *config.Config) error {
 		)
 		m := http.NewServeMux()
 		m.Handle("/metrics", promhttp.Handler())
+		m.Handle("/probe", prober)
+		m.HandleFunc("/mesh", manager.ServeMesh)
+		m.HandleFunc("/-/reload", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost && r.Method != http.MethodPut {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				fmt.Fprintf(w, "This endpoint requires a POST or PUT request.\n")
+				return
+			}
+			result := make(chan error)
+			select {
+			case reloadRequests <- result:
+			case <-ctx.Done():
+				http.Error(w, "shutting down", http.StatusServiceUnavailable)
+				return
+			}
+			if err := <-result; err != nil {
+				http.Error(w, fmt.Sprintf("failed to reload config: %s", err), http.StatusInternalServerError)
+			}
+		})
 		s := http.Server{
 			Addr:    cfg.MetricsAddress,
 			Handler: m,
@@ -171,14 +337,17 @@ func execute(cfg *config.Config) error {
 	}
 
 	{
-		sig := make(chan os.Signal)
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 		g.Add(func() error {
-			signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
-			<-sig
+			select {
+			case <-sig:
+			case <-ctx.Done():
+			}
 			return nil
 		}, func(err error) {
+			signal.Stop(sig)
 			cancel()
-			close(sig)
 		})
 	}
 	if err := g.Run(); err != nil {
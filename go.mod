@@ -9,7 +9,9 @@ require (
 	github.com/ndecker/fritzbox_exporter v0.0.0-20170423140238-834e25023aeb
 	github.com/oklog/run v1.0.0
 	github.com/prometheus/client_golang v1.11.1
+	github.com/prometheus/client_model v0.2.0
 	github.com/urfave/cli/v2 v2.2.0
+	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
@@ -19,7 +21,6 @@ require (
 	github.com/go-logfmt/logfmt v0.5.0 // indirect
 	github.com/golang/protobuf v1.4.3 // indirect
 	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
-	github.com/prometheus/client_model v0.2.0 // indirect
 	github.com/prometheus/common v0.26.0 // indirect
 	github.com/prometheus/procfs v0.6.0 // indirect
 	github.com/russross/blackfriday/v2 v2.0.1 // indirect
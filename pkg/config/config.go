@@ -1,5 +1,14 @@
 package config
 
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
 type Config struct {
 	LogLevel       string
 	FritzBoxURL    string
@@ -8,8 +17,181 @@ type Config struct {
 	MetricsAddress string
 	LogPath        string
 	LokiURL        string
+	StateFile      string
+	FilterOwnLogin bool
+	CacheTTL       time.Duration
+	Collectors     map[string]CollectorConfig
+	Targets        []Target
+	AuthModules    map[string]AuthModule
+	LokiLabels     map[string]string
+	DeviceAliases  map[string]string
+}
+
+var macPattern = regexp.MustCompile(`^([0-9A-Fa-f]{2}[:-]){5}[0-9A-Fa-f]{2}$`)
+
+// CollectorConfig selects a collector and how long its data is cached,
+// an Interval of 0 means CacheTTL.
+type CollectorConfig struct {
+	Enabled  bool
+	Interval time.Duration
+}
+
+// AuthModule holds the credentials used by /probe for boxes that are not
+// configured as target.
+type AuthModule struct {
+	Username string
+	Password string
+}
+
+// Target is a single FRITZ!Box or repeater to scrape. Name ends up in the
+// box label of every metric.
+type Target struct {
+	Name      string
+	URL       string
+	Username  string
+	Password  string
+	LogPath   string
+	StateFile string
 }
 
 func NewConfig() *Config {
-	return &Config{}
+	return &Config{
+		Collectors: make(map[string]CollectorConfig),
+	}
+}
+
+// DeviceName returns the alias configured for the device with the given
+// MAC address, or name if there is none.
+func (c *Config) DeviceName(name string, mac string) string {
+	if alias, ok := c.DeviceAliases[normalizeMAC(mac)]; ok {
+		return alias
+	}
+	return name
+}
+
+func normalizeMAC(mac string) string {
+	return strings.ToUpper(strings.ReplaceAll(mac, "-", ":"))
+}
+
+// ResolveAuthModules builds AuthModules from specs of the form
+// "name=user:password". The global credentials are never used for probes,
+// they would be sent to any host named in a probe request.
+func (c *Config) ResolveAuthModules(specs []string) error {
+	c.AuthModules = make(map[string]AuthModule)
+	for _, spec := range specs {
+		i := strings.Index(spec, "=")
+		if i < 1 {
+			return fmt.Errorf("invalid auth module %q, expected name=user:password", spec)
+		}
+		module := AuthModule{}
+		credentials := spec[i+1:]
+		if j := strings.Index(credentials, ":"); j >= 0 {
+			module.Username = credentials[:j]
+			module.Password = credentials[j+1:]
+		} else {
+			module.Password = credentials
+		}
+		c.AuthModules[spec[:i]] = module
+	}
+	return nil
+}
+
+// ProbeTarget resolves the target parameter of a probe request, either the
+// name of a configured target or an url/host name using the given auth
+// module. Other hosts need a module, so the credentials are only sent to
+// boxes the request names a module for.
+func (c *Config) ProbeTarget(target string, module string) (Target, error) {
+	for _, t := range c.Targets {
+		if t.Name == target {
+			return Target{Name: t.Name, URL: t.URL, Username: t.Username, Password: t.Password}, nil
+		}
+	}
+	if module == "" {
+		return Target{}, fmt.Errorf("target %q is not configured and no auth module is given", target)
+	}
+	auth, ok := c.AuthModules[module]
+	if !ok {
+		return Target{}, fmt.Errorf("unknown auth module %q", module)
+	}
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return Target{}, fmt.Errorf("invalid target %q", target)
+	}
+	return Target{
+		Name:     u.Hostname(),
+		URL:      u.String(),
+		Username: auth.Username,
+		Password: auth.Password,
+	}, nil
+}
+
+// ResolveTargets builds Targets from specs of the form "[name=]url", where url
+// may carry "user:password@" to override the global credentials. Without
+// specs FritzBoxURL is the only target, without both the exporter only
+// answers /probe requests. With more than one target, LogPath and
+// StateFile get the target name appended, so the boxes don't share files.
+func (c *Config) ResolveTargets(specs []string) error {
+	c.Targets = nil
+	if len(specs) == 0 && c.FritzBoxURL != "" {
+		specs = []string{c.FritzBoxURL}
+	}
+	names := map[string]bool{}
+	for _, spec := range specs {
+		t, err := c.parseTarget(spec)
+		if err != nil {
+			return err
+		}
+		if names[t.Name] {
+			return fmt.Errorf("duplicate target name %q", t.Name)
+		}
+		names[t.Name] = true
+		c.Targets = append(c.Targets, t)
+	}
+	for i := range c.Targets {
+		c.Targets[i].LogPath = perTargetPath(c.LogPath, c.Targets[i].Name, len(c.Targets))
+		c.Targets[i].StateFile = perTargetPath(c.StateFile, c.Targets[i].Name, len(c.Targets))
+	}
+	return nil
+}
+
+func (c *Config) parseTarget(spec string) (Target, error) {
+	t := Target{
+		Username: c.Username,
+		Password: c.Password,
+	}
+	rawURL := spec
+	if i := strings.Index(spec, "="); i > 0 && !strings.Contains(spec[:i], "://") {
+		t.Name = spec[:i]
+		rawURL = spec[i+1:]
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return t, fmt.Errorf("invalid target %q: %w", spec, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return t, fmt.Errorf("invalid target %q: url needs scheme and host", spec)
+	}
+	if u.User != nil {
+		t.Username = u.User.Username()
+		if password, ok := u.User.Password(); ok {
+			t.Password = password
+		}
+		u.User = nil
+	}
+	if t.Name == "" {
+		t.Name = u.Hostname()
+	}
+	t.URL = u.String()
+	return t, nil
+}
+
+func perTargetPath(path string, name string, targets int) string {
+	if path == "" || targets < 2 {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + name + ext
 }
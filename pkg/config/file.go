@@ -0,0 +1,279 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// File is the layout of the YAML file given with --config.file. Settings
+// present in the file take precedence over the command line flags.
+type File struct {
+	LogLevel        string                    `yaml:"log_level"`
+	ListenAddress   string                    `yaml:"listen_address"`
+	Username        string                    `yaml:"username"`
+	Password        string                    `yaml:"password"`
+	PasswordFile    string                    `yaml:"password_file"`
+	CacheTTL        time.Duration             `yaml:"cache_ttl"`
+	LogPath         string                    `yaml:"log_path"`
+	StateFile       string                    `yaml:"state_file"`
+	FilterOwnLogins *bool                     `yaml:"filter_own_logins"`
+	Loki            FileLoki                  `yaml:"loki"`
+	Targets         []FileTarget              `yaml:"targets"`
+	AuthModules     map[string]FileAuthModule `yaml:"auth_modules"`
+	Collectors      map[string]FileCollector  `yaml:"collectors"`
+	DeviceAliases   map[string]string         `yaml:"device_aliases"`
+}
+
+// FileLoki configures where box logs are pushed and the labels of the stream.
+type FileLoki struct {
+	URL    string            `yaml:"url"`
+	Labels map[string]string `yaml:"labels"`
+}
+
+// FileTarget is a box to scrape, unset credentials and paths fall back to
+// the global ones.
+type FileTarget struct {
+	Name         string `yaml:"name"`
+	URL          string `yaml:"url"`
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"`
+	LogPath      string `yaml:"log_path"`
+	StateFile    string `yaml:"state_file"`
+}
+
+// FileAuthModule holds credentials used by /probe.
+type FileAuthModule struct {
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"`
+}
+
+// FileCollector enables or disables a collector and sets its interval.
+type FileCollector struct {
+	Enabled  *bool         `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// LoadFile reads and validates the config file at path. collectors are the
+// names of the available collectors, so typos don't go unnoticed.
+func LoadFile(path string, collectors []string) (*File, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{}
+	err = yaml.UnmarshalStrict(data, f)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	err = f.validate(collectors)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return f, nil
+}
+
+func (f *File) validate(collectors []string) error {
+	switch strings.ToLower(f.LogLevel) {
+	case "", "error", "warn", "info", "debug":
+	default:
+		return fmt.Errorf("log_level: unknown level %q, expected error, warn, info or debug", f.LogLevel)
+	}
+	if f.Password != "" && f.PasswordFile != "" {
+		return fmt.Errorf("password and password_file are mutually exclusive")
+	}
+	if f.CacheTTL < 0 {
+		return fmt.Errorf("cache_ttl: must not be negative")
+	}
+	if f.Loki.URL != "" {
+		u, err := url.Parse(f.Loki.URL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("loki.url: %q needs scheme and host", f.Loki.URL)
+		}
+	}
+	names := map[string]bool{}
+	for i, t := range f.Targets {
+		if t.URL == "" {
+			return fmt.Errorf("targets[%d]: url is missing", i)
+		}
+		u, err := url.Parse(t.URL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("targets[%d]: url %q needs scheme and host", i, t.URL)
+		}
+		if u.User != nil {
+			return fmt.Errorf("targets[%d]: url %q must not contain credentials, use username and password", i, t.URL)
+		}
+		if t.Password != "" && t.PasswordFile != "" {
+			return fmt.Errorf("targets[%d]: password and password_file are mutually exclusive", i)
+		}
+		name := t.Name
+		if name == "" {
+			name = u.Hostname()
+		}
+		if names[name] {
+			return fmt.Errorf("targets[%d]: duplicate target name %q", i, name)
+		}
+		names[name] = true
+	}
+	for name, m := range f.AuthModules {
+		if m.Password != "" && m.PasswordFile != "" {
+			return fmt.Errorf("auth_modules.%s: password and password_file are mutually exclusive", name)
+		}
+	}
+	known := map[string]bool{}
+	for _, name := range collectors {
+		known[name] = true
+	}
+	for name, c := range f.Collectors {
+		if !known[name] {
+			return fmt.Errorf("collectors.%s: unknown collector, available are %s", name, strings.Join(collectors, ", "))
+		}
+		if c.Interval < 0 {
+			return fmt.Errorf("collectors.%s.interval: must not be negative", name)
+		}
+	}
+	for mac := range f.DeviceAliases {
+		if !macPattern.MatchString(mac) {
+			return fmt.Errorf("device_aliases: %q is not a MAC address", mac)
+		}
+	}
+	return nil
+}
+
+// ApplyFile overrides the settings given by flags with those of the config
+// file. The targets of the file replace targetSpecs, auth modules are
+// merged with those of authModuleSpecs.
+func (c *Config) ApplyFile(f *File, targetSpecs []string, authModuleSpecs []string) error {
+	if f.LogLevel != "" {
+		c.LogLevel = f.LogLevel
+	}
+	if f.ListenAddress != "" {
+		c.MetricsAddress = f.ListenAddress
+	}
+	if f.Username != "" {
+		c.Username = f.Username
+	}
+	password, err := readPassword(f.Password, f.PasswordFile)
+	if err != nil {
+		return fmt.Errorf("password_file: %w", err)
+	}
+	if password != "" {
+		c.Password = password
+	}
+	if f.CacheTTL > 0 {
+		c.CacheTTL = f.CacheTTL
+	}
+	if f.LogPath != "" {
+		c.LogPath = f.LogPath
+	}
+	if f.StateFile != "" {
+		c.StateFile = f.StateFile
+	}
+	if f.FilterOwnLogins != nil {
+		c.FilterOwnLogin = *f.FilterOwnLogins
+	}
+	if f.Loki.URL != "" {
+		c.LokiURL = f.Loki.URL
+	}
+	c.LokiLabels = f.Loki.Labels
+	for name, fc := range f.Collectors {
+		cc, ok := c.Collectors[name]
+		if !ok {
+			cc.Enabled = true
+		}
+		if fc.Enabled != nil {
+			cc.Enabled = *fc.Enabled
+		}
+		if fc.Interval > 0 {
+			cc.Interval = fc.Interval
+		}
+		c.Collectors[name] = cc
+	}
+	c.DeviceAliases = make(map[string]string, len(f.DeviceAliases))
+	for mac, alias := range f.DeviceAliases {
+		c.DeviceAliases[normalizeMAC(mac)] = alias
+	}
+
+	if len(f.Targets) == 0 {
+		err = c.ResolveTargets(targetSpecs)
+		if err != nil {
+			return err
+		}
+	} else {
+		c.Targets = nil
+		for i, ft := range f.Targets {
+			t, err := c.fileTarget(ft, len(f.Targets))
+			if err != nil {
+				return fmt.Errorf("targets[%d]: %w", i, err)
+			}
+			c.Targets = append(c.Targets, t)
+		}
+	}
+
+	err = c.ResolveAuthModules(authModuleSpecs)
+	if err != nil {
+		return err
+	}
+	for name, fm := range f.AuthModules {
+		password, err := readPassword(fm.Password, fm.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("auth_modules.%s.password_file: %w", name, err)
+		}
+		c.AuthModules[name] = AuthModule{Username: fm.Username, Password: password}
+	}
+	return nil
+}
+
+func (c *Config) fileTarget(ft FileTarget, targets int) (Target, error) {
+	u, err := url.Parse(ft.URL)
+	if err != nil {
+		return Target{}, err
+	}
+	t := Target{
+		Name:      ft.Name,
+		URL:       u.String(),
+		Username:  c.Username,
+		Password:  c.Password,
+		LogPath:   ft.LogPath,
+		StateFile: ft.StateFile,
+	}
+	if t.Name == "" {
+		t.Name = u.Hostname()
+	}
+	if ft.Username != "" {
+		t.Username = ft.Username
+	}
+	password, err := readPassword(ft.Password, ft.PasswordFile)
+	if err != nil {
+		return Target{}, fmt.Errorf("password_file: %w", err)
+	}
+	if password != "" {
+		t.Password = password
+	}
+	if t.LogPath == "" {
+		t.LogPath = perTargetPath(c.LogPath, t.Name, targets)
+	}
+	if t.StateFile == "" {
+		t.StateFile = perTargetPath(c.StateFile, t.Name, targets)
+	}
+	return t, nil
+}
+
+// readPassword returns password, or the content of passwordFile without
+// the trailing newline if set.
+func readPassword(password string, passwordFile string) (string, error) {
+	if passwordFile == "" {
+		return password, nil
+	}
+	data, err := ioutil.ReadFile(passwordFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadFile(t *testing.T) {
+	collectors := []string{"link", "wlan"}
+	for _, tc := range []struct {
+		name string
+		yaml string
+		// want is the error after "parsing <path>: " or "invalid config <path>: "
+		want string
+	}{
+		{
+			name: "valid",
+			yaml: "targets:\n  - name: home\n    url: http://fritz.box\n  - url: http://192.168.1.1\ncollectors:\n  wlan:\n    enabled: false\n",
+		},
+		{
+			name: "unknown key",
+			yaml: "log_level: info\nlisten_adress: :9042\n",
+			want: "parsing %s: yaml: unmarshal errors:\n  line 2: field listen_adress not found in type config.File",
+		},
+		{
+			name: "unknown target key",
+			yaml: "targets:\n  - url: http://fritz.box\n    user: admin\n",
+			want: "parsing %s: yaml: unmarshal errors:\n  line 3: field user not found in type config.FileTarget",
+		},
+		{
+			name: "unknown collector",
+			yaml: "collectors:\n  wlna:\n    enabled: true\n",
+			want: "invalid config %s: collectors.wlna: unknown collector, available are link, wlan",
+		},
+		{
+			name: "duplicate target name",
+			yaml: "targets:\n  - name: home\n    url: http://fritz.box\n  - name: home\n    url: http://192.168.1.1\n",
+			want: `invalid config %s: targets[1]: duplicate target name "home"`,
+		},
+		{
+			name: "duplicate target host name",
+			yaml: "targets:\n  - url: http://fritz.box\n  - url: https://fritz.box:443\n",
+			want: `invalid config %s: targets[1]: duplicate target name "fritz.box"`,
+		},
+		{
+			name: "missing url",
+			yaml: "targets:\n  - url: http://fritz.box\n  - name: office\n",
+			want: "invalid config %s: targets[1]: url is missing",
+		},
+		{
+			name: "url without host",
+			yaml: "targets:\n  - url: fritz.box\n",
+			want: `invalid config %s: targets[0]: url "fritz.box" needs scheme and host`,
+		},
+		{
+			name: "auth module with two passwords",
+			yaml: "auth_modules:\n  office:\n    username: admin\n    password: secret\n    password_file: /etc/fritz\n",
+			want: "invalid config %s: auth_modules.office: password and password_file are mutually exclusive",
+		},
+		{
+			name: "log level",
+			yaml: "log_level: verbose\n",
+			want: `invalid config %s: log_level: unknown level "verbose", expected error, warn, info or debug`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yml")
+			if err := os.WriteFile(path, []byte(tc.yaml), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadFile(path, collectors)
+			if tc.want == "" {
+				if err != nil {
+					t.Errorf("LoadFile() = %v, want no error", err)
+				}
+				return
+			}
+			want := fmt.Sprintf(tc.want, path)
+			if err == nil || err.Error() != want {
+				t.Errorf("LoadFile() = %v, want %q", err, want)
+			}
+		})
+	}
+}
+
+func TestProbeTarget(t *testing.T) {
+	c := NewConfig()
+	c.Targets = []Target{{Name: "home", URL: "http://fritz.box", Username: "admin", Password: "secret"}}
+	if err := c.ResolveAuthModules([]string{"office=probe:pass"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		target string
+		module string
+		want   Target
+		err    string
+	}{
+		{target: "home", want: Target{Name: "home", URL: "http://fritz.box", Username: "admin", Password: "secret"}},
+		{target: "192.168.2.1", module: "office", want: Target{Name: "192.168.2.1", URL: "http://192.168.2.1", Username: "probe", Password: "pass"}},
+		{target: "192.168.2.1", module: "shop", err: `unknown auth module "shop"`},
+		{target: "192.168.2.1", err: `target "192.168.2.1" is not configured and no auth module is given`},
+	} {
+		got, err := c.ProbeTarget(tc.target, tc.module)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("ProbeTarget(%q, %q) = %v, want %q", tc.target, tc.module, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ProbeTarget(%q, %q) = %v", tc.target, tc.module, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ProbeTarget(%q, %q) = %+v, want %+v", tc.target, tc.module, got, tc.want)
+		}
+	}
+}
+
+func TestApplyFile(t *testing.T) {
+	f := &File{
+		Username:    "admin",
+		Password:    "secret",
+		Targets:     []FileTarget{{URL: "http://fritz.box"}, {Name: "office", URL: "http://192.168.2.1", Username: "office"}},
+		AuthModules: map[string]FileAuthModule{"shop": {Username: "probe", Password: "pass"}},
+	}
+	c := NewConfig()
+	c.StateFile = "state.json"
+	if err := c.ApplyFile(f, []string{"ignored=http://192.168.3.1"}, []string{"office=probe:other"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []Target{
+		{Name: "fritz.box", URL: "http://fritz.box", Username: "admin", Password: "secret", StateFile: "state-fritz.box.json"},
+		{Name: "office", URL: "http://192.168.2.1", Username: "office", Password: "secret", StateFile: "state-office.json"},
+	}
+	if !reflect.DeepEqual(c.Targets, want) {
+		t.Errorf("targets %+v, want %+v", c.Targets, want)
+	}
+	if len(c.AuthModules) != 2 || c.AuthModules["shop"].Password != "pass" || c.AuthModules["office"].Password != "other" {
+		t.Errorf("auth modules %+v, want office from the flags and shop from the file", c.AuthModules)
+	}
+
+	for _, tc := range []struct {
+		name        string
+		f           *File
+		authModules []string
+		want        string
+	}{
+		{
+			name:        "auth module flag without name",
+			f:           &File{},
+			authModules: []string{"probe:pass"},
+			want:        `invalid auth module "probe:pass", expected name=user:password`,
+		},
+		{
+			name: "missing password file",
+			f:    &File{AuthModules: map[string]FileAuthModule{"shop": {PasswordFile: filepath.Join(t.TempDir(), "missing")}}},
+			want: "auth_modules.shop.password_file: open %s: no such file or directory",
+		},
+	} {
+		err := NewConfig().ApplyFile(tc.f, nil, tc.authModules)
+		want := tc.want
+		if m, ok := tc.f.AuthModules["shop"]; ok {
+			want = fmt.Sprintf(want, m.PasswordFile)
+		}
+		if err == nil || err.Error() != want {
+			t.Errorf("%s: ApplyFile() = %v, want %q", tc.name, err, want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package fritz
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SmartHomeDevice is a device of the AHA (AVM home automation) device list,
+// e.g. a FRITZ!DECT plug or thermostat. Values the device doesn't have or
+// doesn't report while it's not present are nil.
+type SmartHomeDevice struct {
+	AIN         string
+	Name        string
+	Product     string
+	Firmware    string
+	Present     bool
+	SwitchOn    *float64
+	Power       *float64 // W
+	Energy      *float64 // Wh
+	Voltage     *float64 // V
+	Temperature *float64 // °C, including the offset
+	// thermostat temperatures in °C, Target is nil while the thermostat
+	// is switched off or fully on
+	CurrentTemperature *float64
+	TargetTemperature  *float64
+	Battery            *float64 // %
+	BatteryLow         *float64
+	WindowOpen         *float64
+}
+
+type ahaDeviceList struct {
+	XMLName xml.Name    `xml:"devicelist"`
+	Devices []ahaDevice `xml:"device"`
+}
+
+type ahaDevice struct {
+	Identifier  string `xml:"identifier,attr"`
+	Firmware    string `xml:"fwversion,attr"`
+	ProductName string `xml:"productname,attr"`
+	Present     string `xml:"present"`
+	Name        string `xml:"name"`
+	Battery     string `xml:"battery"`
+	BatteryLow  string `xml:"batterylow"`
+	Switch      *struct {
+		State string `xml:"state"`
+	} `xml:"switch"`
+	PowerMeter *struct {
+		Voltage string `xml:"voltage"`
+		Power   string `xml:"power"`
+		Energy  string `xml:"energy"`
+	} `xml:"powermeter"`
+	Temperature *struct {
+		Celsius string `xml:"celsius"`
+	} `xml:"temperature"`
+	HKR *struct {
+		Tist            string `xml:"tist"`
+		Tsoll           string `xml:"tsoll"`
+		Battery         string `xml:"battery"`
+		BatteryLow      string `xml:"batterylow"`
+		WindowOpenActiv string `xml:"windowopenactiv"`
+	} `xml:"hkr"`
+}
+
+// thermostat values of 253 and 254 mean off and on, not a temperature
+const (
+	hkrOff = 253
+	hkrOn  = 254
+)
+
+// DecodeSmartHomeDevices decodes the answer of
+// homeautoswitch.lua?switchcmd=getdevicelistinfos. Groups are skipped,
+// their values are those of their members.
+func DecodeSmartHomeDevices(body string) ([]SmartHomeDevice, error) {
+	list := ahaDeviceList{}
+	err := xml.Unmarshal([]byte(body), &list)
+	if err != nil {
+		return nil, fmt.Errorf("decoding smart home device list: %v: %w", err, ErrInvalidResponse)
+	}
+	devices := make([]SmartHomeDevice, 0, len(list.Devices))
+	for _, d := range list.Devices {
+		dev := SmartHomeDevice{
+			AIN:        strings.TrimSpace(d.Identifier),
+			Name:       d.Name,
+			Product:    d.ProductName,
+			Firmware:   d.Firmware,
+			Present:    d.Present == "1",
+			Battery:    ahaValue(d.Battery, 1),
+			BatteryLow: ahaValue(d.BatteryLow, 1),
+		}
+		if d.Switch != nil {
+			dev.SwitchOn = ahaValue(d.Switch.State, 1)
+		}
+		if d.PowerMeter != nil {
+			dev.Power = ahaValue(d.PowerMeter.Power, 1000)
+			dev.Energy = ahaValue(d.PowerMeter.Energy, 1)
+			dev.Voltage = ahaValue(d.PowerMeter.Voltage, 1000)
+		}
+		if d.Temperature != nil {
+			dev.Temperature = ahaValue(d.Temperature.Celsius, 10)
+		}
+		if d.HKR != nil {
+			dev.CurrentTemperature = hkrTemperature(d.HKR.Tist)
+			dev.TargetTemperature = hkrTemperature(d.HKR.Tsoll)
+			dev.WindowOpen = ahaValue(d.HKR.WindowOpenActiv, 1)
+			// older firmware reports the battery of thermostats in <hkr> only
+			if dev.Battery == nil {
+				dev.Battery = ahaValue(d.HKR.Battery, 1)
+			}
+			if dev.BatteryLow == nil {
+				dev.BatteryLow = ahaValue(d.HKR.BatteryLow, 1)
+			}
+		}
+		devices = append(devices, dev)
+	}
+	return devices, nil
+}
+
+// ahaValue parses an integer value of the device list in its unit (mW,
+// 0.1 °C, ...) and divides it into the base unit. Unknown values are empty.
+func ahaValue(s string, divisor float64) *float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return nil
+	}
+	v /= divisor
+	return &v
+}
+
+// hkrTemperature converts thermostat temperatures given in 0.5 °C.
+func hkrTemperature(s string) *float64 {
+	v := ahaValue(s, 1)
+	if v == nil || *v == hkrOff || *v == hkrOn {
+		return nil
+	}
+	*v /= 2
+	return v
+}
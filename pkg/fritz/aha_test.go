@@ -0,0 +1,92 @@
+package fritz
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestDecodeSmartHomeDevices(t *testing.T) {
+	// getdevicelistinfos with a plug, two thermostats switched off and fully
+	// on, a thermostat that's not present and a group of the thermostats
+	body, err := os.ReadFile("testdata/devicelist.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	devices, err := DecodeSmartHomeDevices(string(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 4 {
+		t.Fatalf("decoded %d devices, want 4 without the group", len(devices))
+	}
+	byAIN := make(map[string]SmartHomeDevice, len(devices))
+	for _, d := range devices {
+		byAIN[d.AIN] = d
+	}
+
+	value := func(v float64) *float64 { return &v }
+	show := func(v *float64) string {
+		if v == nil {
+			return "nil"
+		}
+		return fmt.Sprint(*v)
+	}
+	for _, tc := range []struct {
+		ain   string
+		name  string
+		value func(SmartHomeDevice) *float64
+		want  *float64
+	}{
+		{"11657 0272633", "switch", func(d SmartHomeDevice) *float64 { return d.SwitchOn }, value(1)},
+		{"11657 0272633", "power", func(d SmartHomeDevice) *float64 { return d.Power }, value(2.35)},
+		{"11657 0272633", "energy", func(d SmartHomeDevice) *float64 { return d.Energy }, value(12345)},
+		{"11657 0272633", "voltage", func(d SmartHomeDevice) *float64 { return d.Voltage }, value(231.745)},
+		// celsius includes the offset already, it's not applied again
+		{"11657 0272633", "temperature", func(d SmartHomeDevice) *float64 { return d.Temperature }, value(21.5)},
+		{"11657 0272633", "thermostat", func(d SmartHomeDevice) *float64 { return d.CurrentTemperature }, nil},
+		{"11657 0272633", "battery", func(d SmartHomeDevice) *float64 { return d.Battery }, nil},
+		{"09995 0123456", "temperature", func(d SmartHomeDevice) *float64 { return d.Temperature }, value(20)},
+		{"09995 0123456", "current", func(d SmartHomeDevice) *float64 { return d.CurrentTemperature }, value(20.5)},
+		{"09995 0123456", "target off", func(d SmartHomeDevice) *float64 { return d.TargetTemperature }, nil},
+		{"09995 0123456", "window open", func(d SmartHomeDevice) *float64 { return d.WindowOpen }, value(1)},
+		{"09995 0123456", "battery", func(d SmartHomeDevice) *float64 { return d.Battery }, value(80)},
+		{"09995 0123456", "battery low", func(d SmartHomeDevice) *float64 { return d.BatteryLow }, value(0)},
+		{"09995 0123456", "power", func(d SmartHomeDevice) *float64 { return d.Power }, nil},
+		{"09995 0654321", "temperature", func(d SmartHomeDevice) *float64 { return d.Temperature }, value(18.5)},
+		{"09995 0654321", "current", func(d SmartHomeDevice) *float64 { return d.CurrentTemperature }, value(18.5)},
+		{"09995 0654321", "target on", func(d SmartHomeDevice) *float64 { return d.TargetTemperature }, nil},
+		// battery from <hkr> only, as older firmware reports it
+		{"09995 0654321", "hkr battery", func(d SmartHomeDevice) *float64 { return d.Battery }, value(20)},
+		{"09995 0654321", "hkr battery low", func(d SmartHomeDevice) *float64 { return d.BatteryLow }, value(1)},
+		{"09995 0111111", "temperature", func(d SmartHomeDevice) *float64 { return d.Temperature }, nil},
+		{"09995 0111111", "current", func(d SmartHomeDevice) *float64 { return d.CurrentTemperature }, nil},
+		{"09995 0111111", "target", func(d SmartHomeDevice) *float64 { return d.TargetTemperature }, value(20)},
+		{"09995 0111111", "battery", func(d SmartHomeDevice) *float64 { return d.Battery }, nil},
+	} {
+		d, ok := byAIN[tc.ain]
+		if !ok {
+			t.Errorf("%s: device not decoded", tc.ain)
+			continue
+		}
+		got := tc.value(d)
+		switch {
+		case got == nil && tc.want == nil:
+		case got == nil || tc.want == nil || *got != *tc.want:
+			t.Errorf("%s %s = %s, want %s", tc.ain, tc.name, show(got), show(tc.want))
+		}
+	}
+
+	plug := byAIN["11657 0272633"]
+	if plug.Name != "Waschmaschine" || plug.Product != "FRITZ!DECT 200" || plug.Firmware != "04.25" || !plug.Present {
+		t.Errorf("plug = %+v, want name, product, firmware and present", plug)
+	}
+	if byAIN["09995 0111111"].Present {
+		t.Errorf("thermostat Keller present, want not present")
+	}
+
+	if _, err := DecodeSmartHomeDevices("<devicelist><device>"); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("DecodeSmartHomeDevices() of a truncated list = %v, want ErrInvalidResponse", err)
+	}
+}
@@ -0,0 +1,55 @@
+package fritz
+
+import (
+	"fmt"
+	"time"
+)
+
+// Backoff computes exponentially growing wait times between failed attempts,
+// starting at Min and capped at Max.
+type Backoff struct {
+	Min    time.Duration
+	Max    time.Duration
+	Factor float64
+
+	attempt int
+}
+
+// DefaultLoginBackoff is used for failed logins. The box doubles its own
+// BlockTime on every failed attempt, so we stay well behind it.
+func DefaultLoginBackoff() Backoff {
+	return Backoff{
+		Min:    15 * time.Second,
+		Max:    15 * time.Minute,
+		Factor: 2,
+	}
+}
+
+// Next returns the wait time for the current attempt and advances the backoff.
+func (b *Backoff) Next() time.Duration {
+	wait := float64(b.Min)
+	for i := 0; i < b.attempt; i++ {
+		wait *= b.Factor
+		if wait >= float64(b.Max) {
+			wait = float64(b.Max)
+			break
+		}
+	}
+	b.attempt++
+	return time.Duration(wait)
+}
+
+// Reset starts over with Min after a successful attempt.
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
+
+// LoginBlockedError is returned when no login attempt is made, because the
+// box reported a BlockTime or the backoff after a failed attempt is not over.
+type LoginBlockedError struct {
+	Until time.Time
+}
+
+func (e *LoginBlockedError) Error() string {
+	return fmt.Sprintf("login blocked until %s", e.Until.Format(time.RFC3339))
+}
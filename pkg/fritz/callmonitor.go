@@ -0,0 +1,175 @@
+package fritz
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// CallMonitorPort is where the box reports calls as they happen, once the
+// call monitor was enabled by dialing #96*5* on a connected phone.
+const CallMonitorPort = 1012
+
+// CallEvent is a line of the call monitor. Fields not sent with the event
+// type are empty.
+type CallEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	// Event is ring (incoming call), call (outgoing call), connect or disconnect
+	Event        string `json:"event"`
+	ConnectionID string `json:"connection_id"`
+	// Extension is the internal phone taking or making the call
+	Extension string `json:"extension,omitempty"`
+	Caller    string `json:"caller,omitempty"`
+	Called    string `json:"called,omitempty"`
+	// Number is the external number of a connect
+	Number string `json:"number,omitempty"`
+	// Line is the line of the call, e.g. SIP0 or POTS
+	Line     string        `json:"line,omitempty"`
+	Duration time.Duration `json:"-"`
+	// DurationSeconds is set for disconnect
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+}
+
+// ParseCallEvent parses a line of the call monitor:
+//
+//	date;RING;id;caller;called;line;
+//	date;CALL;id;extension;caller;called;line;
+//	date;CONNECT;id;extension;number;
+//	date;DISCONNECT;id;seconds;
+func ParseCallEvent(line string) (CallEvent, error) {
+	fields := strings.Split(strings.TrimRight(line, "\r\n"), ";")
+	if len(fields) < 4 {
+		return CallEvent{}, fmt.Errorf("call monitor line %q: %w", line, ErrInvalidResponse)
+	}
+	date, err := time.ParseInLocation("02.01.06 15:04:05", fields[0], time.Local)
+	if err != nil {
+		return CallEvent{}, fmt.Errorf("call monitor line %q: %w", line, ErrInvalidResponse)
+	}
+	e := CallEvent{
+		Timestamp:    date,
+		Event:        strings.ToLower(fields[1]),
+		ConnectionID: fields[2],
+	}
+	field := func(i int) string {
+		if i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+	switch e.Event {
+	case "ring":
+		e.Caller, e.Called, e.Line = field(3), field(4), field(5)
+	case "call":
+		e.Extension, e.Caller, e.Called, e.Line = field(3), field(4), field(5), field(6)
+	case "connect":
+		e.Extension, e.Number = field(3), field(4)
+	case "disconnect":
+		seconds, err := strconv.Atoi(field(3))
+		if err != nil {
+			return CallEvent{}, fmt.Errorf("call monitor line %q: %w", line, ErrInvalidResponse)
+		}
+		e.Duration = time.Duration(seconds) * time.Second
+		e.DurationSeconds = float64(seconds)
+	default:
+		return CallEvent{}, fmt.Errorf("call monitor line %q: unknown event: %w", line, ErrInvalidResponse)
+	}
+	return e, nil
+}
+
+// DefaultCallMonitorBackoff is used between connection attempts to the
+// call monitor.
+func DefaultCallMonitorBackoff() Backoff {
+	return Backoff{
+		Min:    5 * time.Second,
+		Max:    5 * time.Minute,
+		Factor: 2,
+	}
+}
+
+// CallMonitor reads the events of the call monitor at Address (host:port)
+// and reconnects with Backoff when the connection is lost.
+type CallMonitor struct {
+	Address string
+	Backoff Backoff
+	// OnEvent is called for every event, OnError for failed connections
+	// and for malformed lines, which wrap ErrInvalidResponse.
+	OnEvent func(CallEvent)
+	OnError func(error)
+
+	connected int32
+}
+
+// Connected reports whether the connection to the call monitor is up.
+func (m *CallMonitor) Connected() bool {
+	return atomic.LoadInt32(&m.connected) == 1
+}
+
+// Run reads events until ctx is done.
+func (m *CallMonitor) Run(ctx context.Context) {
+	for {
+		err := m.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		wait := m.Backoff.Next()
+		m.onError(fmt.Errorf("%w, reconnecting in %s", err, wait))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (m *CallMonitor) listen(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", m.Address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	atomic.StoreInt32(&m.connected, 1)
+	defer atomic.StoreInt32(&m.connected, 0)
+	m.Backoff.Reset()
+
+	// unblock the scanner on shutdown
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		e, err := ParseCallEvent(scanner.Text())
+		if err != nil {
+			m.onError(err)
+			continue
+		}
+		if m.OnEvent != nil {
+			m.OnEvent(e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("call monitor %s closed the connection", m.Address)
+}
+
+func (m *CallMonitor) onError(err error) {
+	if m.OnError != nil {
+		m.OnError(err)
+	}
+}
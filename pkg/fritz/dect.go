@@ -0,0 +1,101 @@
+package fritz
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DECTService is the TR-064 service with the registered DECT handsets.
+const DECTService = "urn:dslforum-org:service:X_AVM-DE_Dect:1"
+
+// DECTHandset is a DECT handset registered at the box. ID is the internal
+// number of the handset, which stays the same when it is renamed. Firmware
+// and Battery are only known if the handset list of query.lua has them.
+type DECTHandset struct {
+	ID              string
+	Name            string
+	Model           string
+	Firmware        string
+	Registered      bool
+	UpdateAvailable bool
+	Battery         *float64 // %
+}
+
+// DecodeDECTHandset builds DECTHandset from the output arguments of the
+// GetGenericDectEntry action of DECTService.
+func DecodeDECTHandset(args map[string]string) DECTHandset {
+	return DECTHandset{
+		ID:              args["NewID"],
+		Name:            args["NewName"],
+		Model:           args["NewModel"],
+		Registered:      args["NewActive"] == "1",
+		UpdateAvailable: args["NewUpdateAvailable"] == "1",
+	}
+}
+
+// DecodeDECTHandsets builds the handsets from the GetGenericDectEntry
+// answers of all entries. Entries without id and repeated ids are dropped,
+// names aren't unique, the id is what tells the handsets apart.
+func DecodeDECTHandsets(entries []map[string]string) []DECTHandset {
+	seen := make(map[string]bool, len(entries))
+	handsets := make([]DECTHandset, 0, len(entries))
+	for _, args := range entries {
+		h := DecodeDECTHandset(args)
+		if h.ID == "" || seen[h.ID] {
+			continue
+		}
+		seen[h.ID] = true
+		handsets = append(handsets, h)
+	}
+	return handsets
+}
+
+// DECTHandsetDetail is an entry of the dect:settings/Handset list of
+// query.lua, ID is the one GetGenericDectEntry reports as NewID.
+type DECTHandsetDetail struct {
+	ID            string `json:"ID"`
+	FWVersion     string `json:"FWVersion"`
+	BatteryCharge string `json:"BatteryCharge"`
+}
+
+// DecodeDECTHandsetDetails decodes the handsets list of query.lua by id.
+// Firmware without the list answers without it, ErrNotSupported is
+// returned then.
+func DecodeDECTHandsetDetails(body string) (map[string]DECTHandsetDetail, error) {
+	resp := struct {
+		Handsets *[]DECTHandsetDetail `json:"handsets"`
+	}{}
+	err := json.Unmarshal([]byte(body), &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Handsets == nil {
+		return nil, fmt.Errorf("DECT handset list: %w", ErrNotSupported)
+	}
+	details := make(map[string]DECTHandsetDetail, len(*resp.Handsets))
+	for _, d := range *resp.Handsets {
+		id := strings.TrimSpace(d.ID)
+		if id == "" {
+			continue
+		}
+		details[id] = d
+	}
+	return details, nil
+}
+
+// AddDECTHandsetDetails completes the handsets with firmware and battery
+// level of the entries with the same id in details.
+func AddDECTHandsetDetails(handsets []DECTHandset, details map[string]DECTHandsetDetail) {
+	for i := range handsets {
+		d, ok := details[handsets[i].ID]
+		if !ok {
+			continue
+		}
+		handsets[i].Firmware = strings.TrimSpace(d.FWVersion)
+		if battery, err := strconv.ParseFloat(strings.TrimSpace(d.BatteryCharge), 64); err == nil {
+			handsets[i].Battery = &battery
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package fritz
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeDECTHandsets(t *testing.T) {
+	// GetGenericDectEntry answers of a 7590 with two handsets of the same
+	// name, a Gigaset out of reach, an empty slot and a repeated entry
+	body, err := os.ReadFile("testdata/dect.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []map[string]string
+	if err := json.Unmarshal(body, &entries); err != nil {
+		t.Fatal(err)
+	}
+	want := []DECTHandset{
+		{ID: "1", Name: "Mobilteil", Model: "FRITZ!Fon C6", Registered: true},
+		{ID: "2", Name: "Mobilteil", Model: "FRITZ!Fon M2", Registered: true, UpdateAvailable: true},
+		{ID: "3", Name: "Gigaset"},
+	}
+	if got := DecodeDECTHandsets(entries); !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeDECTHandsets() = %+v, want %+v", got, want)
+	}
+}
+
+func TestAddDECTHandsetDetails(t *testing.T) {
+	body, err := os.ReadFile("testdata/dect_handsets.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	details, err := DecodeDECTHandsetDetails(string(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the list is ordered differently and the Gigaset reports nothing
+	handsets := []DECTHandset{
+		{ID: "1", Name: "Mobilteil"},
+		{ID: "2", Name: "Mobilteil"},
+		{ID: "3", Name: "Gigaset"},
+		{ID: "4", Name: "Neu"},
+	}
+	AddDECTHandsetDetails(handsets, details)
+	for _, tc := range []struct {
+		id       string
+		firmware string
+		battery  float64 // -1 for none
+	}{
+		{"1", "04.31", 100},
+		{"2", "04.34", 40},
+		{"3", "", -1},
+		{"4", "", -1},
+	} {
+		var h DECTHandset
+		for _, h = range handsets {
+			if h.ID == tc.id {
+				break
+			}
+		}
+		battery := -1.0
+		if h.Battery != nil {
+			battery = *h.Battery
+		}
+		if h.Firmware != tc.firmware || battery != tc.battery {
+			t.Errorf("handset %s: firmware %q, battery %v, want %q and %v", tc.id, h.Firmware, battery, tc.firmware, tc.battery)
+		}
+	}
+
+	if _, err := DecodeDECTHandsetDetails(`{"pid":"handsets"}`); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("DecodeDECTHandsetDetails() without list = %v, want ErrNotSupported", err)
+	}
+}
@@ -0,0 +1,55 @@
+package fritz
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// DeviceInfoService is the TR-064 service with model, serial number,
+// firmware version and uptime of the box.
+const DeviceInfoService = "urn:dslforum-org:service:DeviceInfo:1"
+
+// UserInterfaceService is the TR-064 service with the firmware update state.
+const UserInterfaceService = "urn:dslforum-org:service:UserInterface:1"
+
+// DeviceInfo is the identity and firmware state of the box.
+type DeviceInfo struct {
+	Model           string
+	Serial          string
+	SoftwareVersion string
+	// Uptime in seconds since the last reboot
+	Uptime float64
+	// NewVersion is empty without update
+	UpdateAvailable bool
+	NewVersion      string
+}
+
+// DecodeDeviceInfo builds DeviceInfo from the output arguments of GetInfo
+// of DeviceInfoService and of UserInterfaceService.
+func DecodeDeviceInfo(info map[string]string, ui map[string]string) (*DeviceInfo, error) {
+	uptime, err := DecodeUptime(info)
+	if err != nil {
+		return nil, err
+	}
+	d := &DeviceInfo{
+		Model:           info["NewModelName"],
+		Serial:          info["NewSerialNumber"],
+		SoftwareVersion: info["NewSoftwareVersion"],
+		Uptime:          uptime,
+	}
+	d.UpdateAvailable = ui["NewUpgradeAvailable"] == "1"
+	if d.UpdateAvailable {
+		d.NewVersion = ui["NewX_AVM-DE_Version"]
+	}
+	return d, nil
+}
+
+// DecodeUptime returns the seconds since the last reboot from the output
+// arguments of GetInfo of DeviceInfoService.
+func DecodeUptime(info map[string]string) (float64, error) {
+	uptime, err := strconv.ParseFloat(info["NewUpTime"], 64)
+	if err != nil {
+		return 0, fmt.Errorf("uptime %q: %w", info["NewUpTime"], ErrInvalidResponse)
+	}
+	return uptime, nil
+}
@@ -0,0 +1,72 @@
+package fritz
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DocsisChannel is a single up- or downstream channel of a cable box.
+// Values the box doesn't report for the channel type are nil, e.g. MSE for
+// DOCSIS 3.1 or errors for upstream channels.
+type DocsisChannel struct {
+	ChannelID     LenientFloat  `json:"channelID"`
+	Channel       LenientFloat  `json:"channel"`
+	Type          string        `json:"type"`
+	Frequency     string        `json:"frequency"`
+	Multiplex     string        `json:"multiplex"`
+	PowerLevel    *LenientFloat `json:"powerLevel"`
+	MSE           *LenientFloat `json:"mse"`
+	MER           *LenientFloat `json:"mer"`
+	Latency       *LenientFloat `json:"latency"`
+	CorrErrors    *LenientFloat `json:"corrErrors"`
+	NonCorrErrors *LenientFloat `json:"nonCorrErrors"`
+}
+
+// DocsisChannels are the channels of one direction by DOCSIS version.
+type DocsisChannels struct {
+	Docsis30 []DocsisChannel `json:"docsis30"`
+	Docsis31 []DocsisChannel `json:"docsis31"`
+}
+
+// DocsisInfo is the channel list of page=docInfo.
+type DocsisInfo struct {
+	Downstream DocsisChannels `json:"channelDs"`
+	Upstream   DocsisChannels `json:"channelUs"`
+}
+
+// DecodeDocsisInfo decodes the cable channel list, boxes without cable
+// modem answer without channels.
+func DecodeDocsisInfo(body string) (*DocsisInfo, error) {
+	resp := struct {
+		Data DocsisInfo `json:"data"`
+	}{}
+	err := json.Unmarshal([]byte(body), &resp)
+	if err != nil {
+		return nil, err
+	}
+	d := resp.Data
+	d.Downstream.Docsis30 = identifiedChannels(d.Downstream.Docsis30)
+	d.Downstream.Docsis31 = identifiedChannels(d.Downstream.Docsis31)
+	d.Upstream.Docsis30 = identifiedChannels(d.Upstream.Docsis30)
+	d.Upstream.Docsis31 = identifiedChannels(d.Upstream.Docsis31)
+	if len(d.Downstream.Docsis30)+len(d.Downstream.Docsis31)+len(d.Upstream.Docsis30)+len(d.Upstream.Docsis31) == 0 {
+		return nil, fmt.Errorf("no DOCSIS channels: %w", ErrNotSupported)
+	}
+	return &d, nil
+}
+
+// identifiedChannels drops channels without channel id and repeated ids,
+// the id is the only label telling the channels of a list apart. DOCSIS
+// doesn't assign id 0, it stands for a missing or empty id.
+func identifiedChannels(channels []DocsisChannel) []DocsisChannel {
+	seen := make(map[LenientFloat]bool, len(channels))
+	kept := channels[:0]
+	for _, c := range channels {
+		if c.ChannelID == 0 || seen[c.ChannelID] {
+			continue
+		}
+		seen[c.ChannelID] = true
+		kept = append(kept, c)
+	}
+	return kept
+}
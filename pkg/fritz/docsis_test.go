@@ -0,0 +1,69 @@
+package fritz
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestDecodeDocsisInfo(t *testing.T) {
+	body, err := os.ReadFile("testdata/docinfo.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := DecodeDocsisInfo(string(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := func(channels []DocsisChannel) []float64 {
+		var ids []float64
+		for _, c := range channels {
+			ids = append(ids, float64(c.ChannelID))
+		}
+		return ids
+	}
+	for _, tc := range []struct {
+		name     string
+		channels []DocsisChannel
+		want     []float64
+	}{
+		// the upstream channel without id is dropped
+		{"upstream 3.0", d.Upstream.Docsis30, []float64{2, 1}},
+		{"upstream 3.1", d.Upstream.Docsis31, []float64{9}},
+		// the second channel 2 and the one with an empty id are dropped
+		{"downstream 3.0", d.Downstream.Docsis30, []float64{1, 2}},
+		{"downstream 3.1", d.Downstream.Docsis31, []float64{33}},
+	} {
+		got := ids(tc.channels)
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: channel ids %v, want %v", tc.name, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: channel ids %v, want %v", tc.name, got, tc.want)
+				break
+			}
+		}
+	}
+
+	ds := d.Downstream.Docsis30[1]
+	if ds.Frequency != "610" || *ds.PowerLevel != 4.9 || *ds.MSE != -36.6 || *ds.CorrErrors != 7 || *ds.NonCorrErrors != 3 || ds.MER != nil {
+		t.Errorf("downstream 3.0 channel 2 = %+v", ds)
+	}
+	ofdm := d.Downstream.Docsis31[0]
+	if *ofdm.MER != 41 || ofdm.MSE != nil || *ofdm.CorrErrors != 1234 {
+		t.Errorf("downstream 3.1 channel 33 = %+v", ofdm)
+	}
+	us := d.Upstream.Docsis30[0]
+	if *us.PowerLevel != 43.8 || us.Type != "64QAM" || us.Multiplex != "ATDMA" || us.CorrErrors != nil {
+		t.Errorf("upstream 3.0 channel 2 = %+v", us)
+	}
+}
+
+func TestDecodeDocsisInfoWithoutCable(t *testing.T) {
+	_, err := DecodeDocsisInfo(`{"pid":"docInfo","data":{"channelUs":{},"channelDs":{}}}`)
+	if !errors.Is(err, ErrNotSupported) {
+		t.Errorf("DecodeDocsisInfo() error = %v, want ErrNotSupported", err)
+	}
+}
@@ -0,0 +1,85 @@
+package fritz
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// DSLService is the TR-064 service with the DSL line state and statistics.
+// GetInfo has the sync and attainable rates, SNR margins and attenuation of
+// both directions, GetStatisticsTotal the CRC, FEC, ES and SES counters and
+// the resyncs. That is every value the collector exports, the data.lua DSL
+// overview pages only add spectrum graphs and DSLAM details on top, which
+// aren't exported.
+const DSLService = "urn:dslforum-org:service:WANDSLInterfaceConfig:1"
+
+// DSLDirection holds the line values of one direction.
+type DSLDirection struct {
+	// SyncRate and AttainableRate in bits/second
+	SyncRate       float64
+	AttainableRate float64
+	// SNRMargin and Attenuation in dB
+	SNRMargin   float64
+	Attenuation float64
+	CRCErrors   float64
+	FECErrors   float64
+}
+
+// DSLInfo is the state of the DSL line as reported by the box. Errors
+// counted by the box are downstream, those of the DSLAM (ATU-C) upstream.
+type DSLInfo struct {
+	Status              string
+	Upstream            DSLDirection
+	Downstream          DSLDirection
+	ErroredSeconds      float64
+	SeverelyErroredSecs float64
+	Resyncs             float64
+	LossOfFraming       float64
+	InitErrors          float64
+	InitTimeouts        float64
+}
+
+// DecodeDSLInfo builds DSLInfo from the output arguments of the GetInfo
+// and GetStatisticsTotal actions of DSLService.
+func DecodeDSLInfo(info map[string]string, stats map[string]string) (*DSLInfo, error) {
+	d := &DSLInfo{Status: info["NewStatus"]}
+	values := []struct {
+		args  map[string]string
+		name  string
+		value *float64
+		// rates are reported in kbit/s, margins and attenuation in 0.1 dB
+		scale   float64
+		divisor float64
+	}{
+		{info, "NewUpstreamCurrRate", &d.Upstream.SyncRate, 1000, 1},
+		{info, "NewDownstreamCurrRate", &d.Downstream.SyncRate, 1000, 1},
+		{info, "NewUpstreamMaxRate", &d.Upstream.AttainableRate, 1000, 1},
+		{info, "NewDownstreamMaxRate", &d.Downstream.AttainableRate, 1000, 1},
+		{info, "NewUpstreamNoiseMargin", &d.Upstream.SNRMargin, 1, 10},
+		{info, "NewDownstreamNoiseMargin", &d.Downstream.SNRMargin, 1, 10},
+		{info, "NewUpstreamAttenuation", &d.Upstream.Attenuation, 1, 10},
+		{info, "NewDownstreamAttenuation", &d.Downstream.Attenuation, 1, 10},
+		{stats, "NewCRCErrors", &d.Downstream.CRCErrors, 1, 1},
+		{stats, "NewATUCCRCErrors", &d.Upstream.CRCErrors, 1, 1},
+		{stats, "NewFECErrors", &d.Downstream.FECErrors, 1, 1},
+		{stats, "NewATUCFECErrors", &d.Upstream.FECErrors, 1, 1},
+		{stats, "NewErroredSecs", &d.ErroredSeconds, 1, 1},
+		{stats, "NewSeverelyErroredSecs", &d.SeverelyErroredSecs, 1, 1},
+		{stats, "NewLinkRetrain", &d.Resyncs, 1, 1},
+		{stats, "NewLossOfFraming", &d.LossOfFraming, 1, 1},
+		{stats, "NewInitErrors", &d.InitErrors, 1, 1},
+		{stats, "NewInitTimeouts", &d.InitTimeouts, 1, 1},
+	}
+	for _, v := range values {
+		raw, ok := v.args[v.name]
+		if !ok || raw == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", v.name, err)
+		}
+		*v.value = f * v.scale / v.divisor
+	}
+	return d, nil
+}
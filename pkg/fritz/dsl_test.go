@@ -0,0 +1,79 @@
+package fritz
+
+import (
+	"testing"
+)
+
+func TestDecodeDSLInfo(t *testing.T) {
+	// GetInfo and GetStatisticsTotal of a 7590 on VDSL2 vectoring
+	info := map[string]string{
+		"NewEnable":                "1",
+		"NewStatus":                "Up",
+		"NewDataPath":              "Interleaved",
+		"NewUpstreamCurrRate":      "40000",
+		"NewDownstreamCurrRate":    "116797",
+		"NewUpstreamMaxRate":       "46942",
+		"NewDownstreamMaxRate":     "134570",
+		"NewUpstreamNoiseMargin":   "61",
+		"NewDownstreamNoiseMargin": "93",
+		"NewUpstreamAttenuation":   "74",
+		"NewDownstreamAttenuation": "123",
+		"NewATURVendor":            "41564d00",
+		"NewATURCountry":           "0400",
+		"NewUpstreamPower":         "498",
+		"NewDownstreamPower":       "513",
+	}
+	stats := map[string]string{
+		"NewReceiveBlocks":       "1043532",
+		"NewTransmitBlocks":      "6723109",
+		"NewCellDelin":           "0",
+		"NewLinkRetrain":         "2",
+		"NewInitErrors":          "1",
+		"NewInitTimeouts":        "3",
+		"NewLossOfFraming":       "0",
+		"NewErroredSecs":         "17",
+		"NewSeverelyErroredSecs": "4",
+		"NewFECErrors":           "52011",
+		"NewATUCFECErrors":       "93",
+		"NewHECErrors":           "0",
+		"NewATUCHECErrors":       "0",
+		"NewCRCErrors":           "38",
+		"NewATUCCRCErrors":       "6",
+	}
+	d, err := DecodeDSLInfo(info, stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DSLInfo{
+		Status: "Up",
+		Upstream: DSLDirection{
+			SyncRate:       40000000,
+			AttainableRate: 46942000,
+			SNRMargin:      6.1,
+			Attenuation:    7.4,
+			CRCErrors:      6,
+			FECErrors:      93,
+		},
+		Downstream: DSLDirection{
+			SyncRate:       116797000,
+			AttainableRate: 134570000,
+			SNRMargin:      9.3,
+			Attenuation:    12.3,
+			CRCErrors:      38,
+			FECErrors:      52011,
+		},
+		ErroredSeconds:      17,
+		SeverelyErroredSecs: 4,
+		Resyncs:             2,
+		LossOfFraming:       0,
+		InitErrors:          1,
+		InitTimeouts:        3,
+	}
+	if *d != want {
+		t.Errorf("DecodeDSLInfo() = %+v, want %+v", *d, want)
+	}
+
+	if _, err := DecodeDSLInfo(map[string]string{"NewUpstreamCurrRate": "n/a"}, nil); err == nil {
+		t.Error("DecodeDSLInfo() accepted a non-numeric rate")
+	}
+}
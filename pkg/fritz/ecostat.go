@@ -0,0 +1,139 @@
+package fritz
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// EcoStat holds the latest values of the statistics of page=ecoStat, which
+// the box records for the last 24 hours. Values missing from the answer are nil.
+type EcoStat struct {
+	CPULoad        *float64 // %
+	CPUTemperature *float64 // °C
+	// RAM usage in % by fixed (firmware), dynamic (runtime) and free memory
+	RAMFixed   *float64
+	RAMDynamic *float64
+	RAMFree    *float64
+}
+
+// ecoStatSeries is one chart of page=ecoStat, labels names the series in
+// the language of the web interface.
+type ecoStatSeries struct {
+	Series [][]float64 `json:"series"`
+	Labels []string    `json:"labels"`
+}
+
+// latest returns the newest value of the nth series.
+func (e ecoStatSeries) latest(n int) *float64 {
+	if n < 0 || n >= len(e.Series) || len(e.Series[n]) == 0 {
+		return nil
+	}
+	v := e.Series[n][len(e.Series[n])-1]
+	return &v
+}
+
+// labelled returns the newest value of the series whose label contains one
+// of names.
+func (e ecoStatSeries) labelled(names ...string) *float64 {
+	for i, label := range e.Labels {
+		label = strings.ToLower(label)
+		for _, name := range names {
+			if strings.Contains(label, name) {
+				return e.latest(i)
+			}
+		}
+	}
+	return nil
+}
+
+// DecodeEcoStat decodes the answer of page=ecoStat.
+func DecodeEcoStat(body string) (*EcoStat, error) {
+	resp := struct {
+		Data struct {
+			CPUUtil  ecoStatSeries `json:"cpuutil"`
+			CPUTemp  ecoStatSeries `json:"cputemp"`
+			RAMUsage ecoStatSeries `json:"ramusage"`
+		} `json:"data"`
+	}{}
+	err := json.Unmarshal([]byte(body), &resp)
+	if err != nil {
+		return nil, err
+	}
+	d := resp.Data
+	e := &EcoStat{
+		CPULoad:        d.CPUUtil.latest(0),
+		CPUTemperature: d.CPUTemp.latest(0),
+		RAMFixed:       d.RAMUsage.labelled("fest", "fixed"),
+		RAMDynamic:     d.RAMUsage.labelled("dynamisch", "dynamic"),
+		RAMFree:        d.RAMUsage.labelled("frei", "free"),
+	}
+	if e.CPULoad == nil && e.CPUTemperature == nil && e.RAMFixed == nil && e.RAMDynamic == nil && e.RAMFree == nil {
+		return nil, fmt.Errorf("ecoStat without statistics: %w", ErrInvalidResponse)
+	}
+	return e, nil
+}
+
+// EnergyUsage is the share of the maximum power consumption of the box a
+// subsystem currently draws, as shown on page=energy.
+type EnergyUsage struct {
+	// Subsystem is total, cpu, wlan, dsl, lan, usb, dect or telephony, or
+	// the name the box uses for others in lower case
+	Subsystem string
+	Percent   float64
+}
+
+// energySubsystems maps the German and English names of page=energy to
+// stable label values.
+var energySubsystems = map[string]string{
+	"fritz!box gesamtsystem": "total",
+	"gesamtsystem":           "total",
+	"fritz!box total system": "total",
+	"total system":           "total",
+	"hauptprozessor":         "cpu",
+	"main processor":         "cpu",
+	"wlan":                   "wlan",
+	"wi-fi":                  "wlan",
+	"dsl":                    "dsl",
+	"lan":                    "lan",
+	"usb":                    "usb",
+	"dect":                   "dect",
+	"telefonie":              "telephony",
+	"telephony":              "telephony",
+}
+
+// DecodeEnergyUsage decodes the answer of page=energy. A subsystem listed
+// twice is reported once, with the first value.
+func DecodeEnergyUsage(body string) ([]EnergyUsage, error) {
+	resp := struct {
+		Data struct {
+			Drain []struct {
+				Name    string   `json:"name"`
+				ActPerc *float64 `json:"actPerc"`
+			} `json:"drain"`
+		} `json:"data"`
+	}{}
+	err := json.Unmarshal([]byte(body), &resp)
+	if err != nil {
+		return nil, err
+	}
+	usage := make([]EnergyUsage, 0, len(resp.Data.Drain))
+	seen := make(map[string]bool, len(resp.Data.Drain))
+	for _, d := range resp.Data.Drain {
+		subsystem := energySubsystem(d.Name)
+		if d.ActPerc == nil || subsystem == "" || seen[subsystem] {
+			continue
+		}
+		seen[subsystem] = true
+		usage = append(usage, EnergyUsage{Subsystem: subsystem, Percent: *d.ActPerc})
+	}
+	return usage, nil
+}
+
+func energySubsystem(name string) string {
+	n := strings.ToLower(strings.TrimSpace(name))
+	if subsystem, ok := energySubsystems[n]; ok {
+		return subsystem
+	}
+	return n
+}
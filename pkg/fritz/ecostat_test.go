@@ -0,0 +1,67 @@
+package fritz
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeEcoStat(t *testing.T) {
+	body, err := os.ReadFile("testdata/ecostat.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := DecodeEcoStat(string(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, tc := range map[string]struct {
+		got  *float64
+		want float64
+	}{
+		"CPULoad":        {e.CPULoad, 12},
+		"CPUTemperature": {e.CPUTemperature, 65},
+		"RAMFixed":       {e.RAMFixed, 29},
+		"RAMDynamic":     {e.RAMDynamic, 43},
+		"RAMFree":        {e.RAMFree, 28},
+	} {
+		if tc.got == nil || *tc.got != tc.want {
+			t.Errorf("%s = %v, want %v", name, tc.got, tc.want)
+		}
+	}
+
+	// the RAM series are found by label, not by position
+	e, err = DecodeEcoStat(`{"data":{"ramusage":{"series":[[30],[20],[50]],"labels":["Free memory","Fixed memory","Dynamic memory"]}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *e.RAMFree != 30 || *e.RAMFixed != 20 || *e.RAMDynamic != 50 {
+		t.Errorf("RAM fixed/dynamic/free = %v/%v/%v, want 20/50/30", *e.RAMFixed, *e.RAMDynamic, *e.RAMFree)
+	}
+}
+
+func TestDecodeEnergyUsage(t *testing.T) {
+	body, err := os.ReadFile("testdata/energy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage, err := DecodeEnergyUsage(string(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []EnergyUsage{
+		{"total", 47},
+		{"cpu", 32},
+		{"wlan", 61},
+		{"dsl", 100},
+		{"lan", 10},
+		{"usb", 0},
+		// Telefonie and DECT don't share a label, the second Telefonie is dropped
+		{"telephony", 5},
+		{"dect", 12},
+		{"glasfaser", 55},
+	}
+	if !reflect.DeepEqual(usage, want) {
+		t.Errorf("DecodeEnergyUsage() = %v, want %v", usage, want)
+	}
+}
@@ -2,11 +2,24 @@ package fritz
 
 import (
 	"encoding/json"
+	"net"
 	"regexp"
+	"strings"
 	"time"
 )
 
-var helpLinkSid = regexp.MustCompile("sid=.*&")
+var (
+	helpLinkSid = regexp.MustCompile("sid=.*&")
+	// successful web interface logins and logouts, german and english
+	// firmware. The lines end with the address, so failed attempts, which
+	// append the reason, don't match.
+	uiLoginMessages = []*regexp.Regexp{
+		regexp.MustCompile(`^Anmeldung des Benutzers (\S+) an der FRITZ!Box-Benutzeroberfläche von IP-Adresse (\S+?)\.?$`),
+		regexp.MustCompile(`^Abmeldung des Benutzers (\S+) von der FRITZ!Box-Benutzeroberfläche von IP-Adresse (\S+?)\.?$`),
+		regexp.MustCompile(`^User (\S+) logged in to the FRITZ!Box user interface from IP address (\S+?)\.?$`),
+		regexp.MustCompile(`^User (\S+) logged out of the FRITZ!Box user interface from IP address (\S+?)\.?$`),
+	}
+)
 
 type Logs struct {
 	Data Data `json:"data"`
@@ -89,6 +102,36 @@ func (l *Logs) Decode(body string) error {
 	return nil
 }
 
+// DropLogins removes the successful web interface logins and logouts of
+// user from ip, the address the exporter connects to the box from, from the
+// decoded log lines. Failed attempts and logins from other addresses are
+// kept, nothing is dropped if user or ip is empty.
+func (l *Logs) DropLogins(user string, ip string) {
+	if user == "" || net.ParseIP(ip) == nil {
+		return
+	}
+	kept := l.Data.LogLines[:0]
+	for _, line := range l.Data.LogLines {
+		if isLoginOf(line.Message, user, ip) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	l.Data.LogLines = kept
+}
+
+func isLoginOf(message string, user string, ip string) bool {
+	for _, pattern := range uiLoginMessages {
+		m := pattern.FindStringSubmatch(message)
+		if m == nil {
+			continue
+		}
+		from := net.ParseIP(m[2])
+		return strings.Trim(m[1], "\"") == user && from != nil && from.Equal(net.ParseIP(ip))
+	}
+	return false
+}
+
 func (l *Logs) Encode() (string, error) {
 	resBytes, err := json.Marshal(l.Data.LogLines)
 	if err != nil {
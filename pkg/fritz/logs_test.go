@@ -0,0 +1,58 @@
+package fritz
+
+import (
+	"testing"
+)
+
+func TestDropLogins(t *testing.T) {
+	messages := []struct {
+		message string
+		own     bool
+	}{
+		{"Anmeldung des Benutzers exporter an der FRITZ!Box-Benutzeroberfläche von IP-Adresse 192.168.178.20.", true},
+		{"Abmeldung des Benutzers exporter von der FRITZ!Box-Benutzeroberfläche von IP-Adresse 192.168.178.20.", true},
+		{"User exporter logged in to the FRITZ!Box user interface from IP address 192.168.178.20.", true},
+		{"User exporter logged out of the FRITZ!Box user interface from IP address 192.168.178.20.", true},
+		// failed attempts with the exporter's user name
+		{"Anmeldung des Benutzers exporter an der FRITZ!Box-Benutzeroberfläche von IP-Adresse 192.168.178.20 gescheitert (ungültiges Kennwort).", false},
+		{"User exporter failed to log in to the FRITZ!Box user interface from IP address 192.168.178.20 (invalid password).", false},
+		// the exporter's user name from another address
+		{"Anmeldung des Benutzers exporter an der FRITZ!Box-Benutzeroberfläche von IP-Adresse 192.168.178.99.", false},
+		{"User exporter logged in to the FRITZ!Box user interface from IP address 203.0.113.7.", false},
+		// other users
+		{"Anmeldung des Benutzers admin an der FRITZ!Box-Benutzeroberfläche von IP-Adresse 192.168.178.20.", false},
+		{"User exporter2 logged in to the FRITZ!Box user interface from IP address 192.168.178.20.", false},
+		{"Anmeldung an der FRITZ!Box-Benutzeroberfläche von IP-Adresse 192.168.178.20.", false},
+		{"Internetverbindung wurde erfolgreich hergestellt. IP-Adresse: 203.0.113.1", false},
+	}
+	logs := func() *Logs {
+		l := &Logs{}
+		for _, m := range messages {
+			l.Data.LogLines = append(l.Data.LogLines, LogLine{Message: m.message})
+		}
+		return l
+	}
+
+	l := logs()
+	l.DropLogins("exporter", "192.168.178.20")
+	kept := make(map[string]bool)
+	for _, line := range l.Data.LogLines {
+		kept[line.Message] = true
+	}
+	for _, m := range messages {
+		if kept[m.message] == m.own {
+			t.Errorf("DropLogins() kept %t: %q", kept[m.message], m.message)
+		}
+	}
+
+	for _, tc := range []struct{ user, ip string }{
+		{"", "192.168.178.20"},
+		{"exporter", ""},
+	} {
+		l := logs()
+		l.DropLogins(tc.user, tc.ip)
+		if len(l.Data.LogLines) != len(messages) {
+			t.Errorf("DropLogins(%q, %q) dropped %d lines", tc.user, tc.ip, len(messages)-len(l.Data.LogLines))
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package fritz
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// HostsService is the TR-064 service with the host list and mesh list.
+const HostsService = "urn:dslforum-org:service:Hosts:1"
+
+// MeshList is the mesh list as returned by the path of X_AVM-DE_GetMeshListPath.
+type MeshList struct {
+	SchemaVersion string     `json:"schema_version"`
+	Nodes         []MeshNode `json:"nodes"`
+}
+
+// MeshNode is a box, repeater or client in the mesh list.
+type MeshNode struct {
+	UID        string          `json:"uid"`
+	Name       string          `json:"device_name"`
+	MAC        string          `json:"device_mac_address"`
+	Model      string          `json:"device_model"`
+	Role       string          `json:"mesh_role"`
+	Meshed     bool            `json:"is_meshed"`
+	Interfaces []MeshInterface `json:"node_interfaces"`
+}
+
+// MeshInterface is a network interface of a node.
+type MeshInterface struct {
+	UID    string     `json:"uid"`
+	Name   string     `json:"name"`
+	Type   string     `json:"type"`
+	MAC    string     `json:"mac_address"`
+	SSID   string     `json:"ssid"`
+	OpMode string     `json:"opmode"`
+	Links  []MeshLink `json:"node_links"`
+}
+
+// MeshLink connects the interfaces of two nodes, rates are in kbit/s.
+type MeshLink struct {
+	UID           string  `json:"uid"`
+	Type          string  `json:"type"`
+	State         string  `json:"state"`
+	Node1UID      string  `json:"node_1_uid"`
+	Node2UID      string  `json:"node_2_uid"`
+	Interface1UID string  `json:"node_interface_1_uid"`
+	Interface2UID string  `json:"node_interface_2_uid"`
+	MaxDataRateRx float64 `json:"max_data_rate_rx"`
+	MaxDataRateTx float64 `json:"max_data_rate_tx"`
+	CurDataRateRx float64 `json:"cur_data_rate_rx"`
+	CurDataRateTx float64 `json:"cur_data_rate_tx"`
+}
+
+// MeshGraph is the mesh list as tree below the mesh master. Every node
+// reachable over connected links has its uplink towards the master.
+type MeshGraph struct {
+	Root  *MeshNode
+	Nodes map[string]*MeshNode
+	// Links holds every link once, sorted by UID
+	Links []MeshLink
+	// Uplinks maps a node UID to the link towards its parent
+	Uplinks map[string]MeshLink
+	// Parents maps a node UID to its parent node UID
+	Parents map[string]string
+	// Hops maps a node UID to its distance to the master
+	Hops map[string]int
+}
+
+// DecodeMeshList decodes the mesh list and builds the graph.
+func DecodeMeshList(data []byte) (*MeshGraph, error) {
+	list := MeshList{}
+	err := json.Unmarshal(data, &list)
+	if err != nil {
+		return nil, err
+	}
+	return NewMeshGraph(list)
+}
+
+// NewMeshGraph builds the tree of list starting at the mesh master.
+func NewMeshGraph(list MeshList) (*MeshGraph, error) {
+	g := &MeshGraph{
+		Nodes:   make(map[string]*MeshNode, len(list.Nodes)),
+		Uplinks: make(map[string]MeshLink),
+		Parents: make(map[string]string),
+		Hops:    make(map[string]int),
+	}
+	links := map[string]MeshLink{}
+	for i := range list.Nodes {
+		n := &list.Nodes[i]
+		// a node listed twice keeps its first entry, the links of both count
+		if _, ok := g.Nodes[n.UID]; !ok {
+			g.Nodes[n.UID] = n
+			if n.Role == "master" && g.Root == nil {
+				g.Root = n
+			}
+		}
+		for _, iface := range n.Interfaces {
+			for _, l := range iface.Links {
+				// both ends list the link, often only one of them with rates
+				if _, ok := links[l.UID]; ok && l.MaxDataRateRx+l.MaxDataRateTx+l.CurDataRateRx+l.CurDataRateTx == 0 {
+					continue
+				}
+				links[l.UID] = l
+			}
+		}
+	}
+	if g.Root == nil {
+		return nil, fmt.Errorf("mesh list without master: %w", ErrInvalidResponse)
+	}
+	for _, l := range links {
+		g.Links = append(g.Links, l)
+	}
+	sort.Slice(g.Links, func(i, j int) bool { return g.Links[i].UID < g.Links[j].UID })
+
+	// breadth first, so every node hangs off the closest path to the master
+	g.Hops[g.Root.UID] = 0
+	queue := []string{g.Root.UID}
+	for len(queue) > 0 {
+		uid := queue[0]
+		queue = queue[1:]
+		for _, l := range g.Links {
+			if !strings.EqualFold(l.State, "CONNECTED") {
+				continue
+			}
+			var other string
+			switch uid {
+			case l.Node1UID:
+				other = l.Node2UID
+			case l.Node2UID:
+				other = l.Node1UID
+			default:
+				continue
+			}
+			if _, seen := g.Hops[other]; seen || g.Nodes[other] == nil {
+				continue
+			}
+			g.Hops[other] = g.Hops[uid] + 1
+			g.Parents[other] = uid
+			g.Uplinks[other] = l
+			queue = append(queue, other)
+		}
+	}
+	return g, nil
+}
+
+// meshGraphNode is a node of the JSON export.
+type meshGraphNode struct {
+	UID        string `json:"uid"`
+	Name       string `json:"name"`
+	MAC        string `json:"mac"`
+	Model      string `json:"model,omitempty"`
+	Role       string `json:"role"`
+	Parent     string `json:"parent,omitempty"`
+	Hops       *int   `json:"hops,omitempty"`
+	UplinkType string `json:"uplink_type,omitempty"`
+}
+
+// meshGraphLink is a link of the JSON export, rates are in kbit/s.
+type meshGraphLink struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Type   string  `json:"type"`
+	State  string  `json:"state"`
+	RateRx float64 `json:"rate_rx"`
+	RateTx float64 `json:"rate_tx"`
+}
+
+// MarshalJSON exports the graph as lists of nodes and links.
+func (g *MeshGraph) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Nodes []meshGraphNode `json:"nodes"`
+		Links []meshGraphLink `json:"links"`
+	}{}
+	for _, uid := range g.sortedUIDs() {
+		n := g.Nodes[uid]
+		node := meshGraphNode{
+			UID:        n.UID,
+			Name:       n.Name,
+			MAC:        n.MAC,
+			Model:      n.Model,
+			Role:       n.Role,
+			Parent:     g.Parents[uid],
+			UplinkType: g.Uplinks[uid].Type,
+		}
+		if hops, ok := g.Hops[uid]; ok {
+			node.Hops = &hops
+		}
+		out.Nodes = append(out.Nodes, node)
+	}
+	for _, l := range g.Links {
+		out.Links = append(out.Links, meshGraphLink{
+			From:   l.Node1UID,
+			To:     l.Node2UID,
+			Type:   l.Type,
+			State:  l.State,
+			RateRx: l.CurDataRateRx,
+			RateTx: l.CurDataRateTx,
+		})
+	}
+	return json.Marshal(out)
+}
+
+// DOT exports the tree in the Graphviz DOT language, disconnected links
+// are drawn dashed.
+func (g *MeshGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("graph mesh {\n")
+	for _, uid := range g.sortedUIDs() {
+		n := g.Nodes[uid]
+		label := n.Name
+		if n.Model != "" {
+			label += "\n" + n.Model
+		}
+		shape := "ellipse"
+		if n.Meshed {
+			shape = "box"
+		}
+		fmt.Fprintf(&b, "  %q [label=%q, shape=%s];\n", uid, label, shape)
+	}
+	for _, l := range g.Links {
+		label := l.Type
+		if l.CurDataRateRx > 0 || l.CurDataRateTx > 0 {
+			label += fmt.Sprintf("\n%.0f/%.0f Mbit/s", l.CurDataRateRx/1000, l.CurDataRateTx/1000)
+		}
+		style := "solid"
+		if !strings.EqualFold(l.State, "CONNECTED") {
+			style = "dashed"
+		}
+		fmt.Fprintf(&b, "  %q -- %q [label=%q, style=%s];\n", l.Node1UID, l.Node2UID, label, style)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func (g *MeshGraph) sortedUIDs() []string {
+	uids := make([]string, 0, len(g.Nodes))
+	for uid := range g.Nodes {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	return uids
+}
@@ -0,0 +1,142 @@
+package fritz
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// testdata/meshlist.json is a box with two repeaters, the second one
+// connected to the box and to the first repeater, a laptop with two nodes
+// behind the first repeater, a printer on a disconnected link and the first
+// repeater listed again under an older name.
+func readMeshGraph(t *testing.T) *MeshGraph {
+	t.Helper()
+	data, err := os.ReadFile("testdata/meshlist.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := DecodeMeshList(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestNewMeshGraph(t *testing.T) {
+	g := readMeshGraph(t)
+	if g.Root == nil || g.Root.UID != "n-1" {
+		t.Fatalf("root = %+v, want the master n-1", g.Root)
+	}
+	if len(g.Nodes) != 6 {
+		t.Errorf("%d nodes, want 6", len(g.Nodes))
+	}
+	if name := g.Nodes["n-2"].Name; name != "repeater" {
+		t.Errorf("node listed twice named %q, want the first entry", name)
+	}
+
+	links := make([]string, 0, len(g.Links))
+	for _, l := range g.Links {
+		links = append(links, l.UID)
+	}
+	if want := []string{"nl-1", "nl-2", "nl-3", "nl-4", "nl-5", "nl-6"}; !reflect.DeepEqual(links, want) {
+		t.Errorf("links %v, want %v", links, want)
+	}
+	if l := g.Links[0]; l.CurDataRateRx != 650000 || l.MaxDataRateTx != 866000 {
+		t.Errorf("link listed by both ends = %+v, want the rates of the end that has them", l)
+	}
+
+	for _, tc := range []struct {
+		uid    string
+		parent string
+		hops   int
+		uplink string
+	}{
+		{"n-2", "n-1", 1, "nl-1"},
+		{"n-3", "n-2", 2, "nl-2"},
+		{"n-5", "n-2", 2, "nl-4"},
+		// over the box, not over the first repeater
+		{"n-6", "n-1", 1, "nl-5"},
+	} {
+		if parent := g.Parents[tc.uid]; parent != tc.parent {
+			t.Errorf("%s: parent %q, want %q", tc.uid, parent, tc.parent)
+		}
+		if hops, ok := g.Hops[tc.uid]; !ok || hops != tc.hops {
+			t.Errorf("%s: hops %d (%v), want %d", tc.uid, hops, ok, tc.hops)
+		}
+		if uplink := g.Uplinks[tc.uid].UID; uplink != tc.uplink {
+			t.Errorf("%s: uplink %q, want %q", tc.uid, uplink, tc.uplink)
+		}
+	}
+	if hops, ok := g.Hops["n-1"]; !ok || hops != 0 {
+		t.Errorf("master: hops %d (%v), want 0", hops, ok)
+	}
+	if _, ok := g.Parents["n-1"]; ok {
+		t.Errorf("master has a parent")
+	}
+	// the printer is only reachable over a disconnected link
+	_, hasHops := g.Hops["n-4"]
+	_, hasParent := g.Parents["n-4"]
+	if hasHops || hasParent {
+		t.Errorf("unreachable node has hops %v and parent %v, want neither", hasHops, hasParent)
+	}
+
+	_, err := NewMeshGraph(MeshList{Nodes: []MeshNode{{UID: "n-3", Role: "unknown"}}})
+	if !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("NewMeshGraph() without master = %v, want ErrInvalidResponse", err)
+	}
+}
+
+func TestMeshGraphMarshalJSON(t *testing.T) {
+	data, err := json.Marshal(readMeshGraph(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Nodes []map[string]interface{} `json:"nodes"`
+		Links []map[string]interface{} `json:"links"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Nodes) != 6 || len(out.Links) != 6 {
+		t.Fatalf("%d nodes and %d links, want 6 each", len(out.Nodes), len(out.Links))
+	}
+	for i, want := range []map[string]interface{}{
+		{"uid": "n-1", "name": "fritz.box", "mac": "AA:00:00:00:00:01", "model": "FRITZ!Box 7590", "role": "master", "hops": 0.0},
+		{"uid": "n-2", "name": "repeater", "mac": "AA:00:00:00:00:02", "model": "FRITZ!Repeater 2400", "role": "slave", "parent": "n-1", "hops": 1.0, "uplink_type": "WLAN"},
+		{"uid": "n-3", "name": "laptop", "mac": "AA:00:00:00:00:03", "role": "unknown", "parent": "n-2", "hops": 2.0, "uplink_type": "WLAN"},
+		{"uid": "n-4", "name": "printer", "mac": "AA:00:00:00:00:04", "role": "unknown"},
+	} {
+		if !reflect.DeepEqual(out.Nodes[i], want) {
+			t.Errorf("node %d = %v, want %v", i, out.Nodes[i], want)
+		}
+	}
+	want := map[string]interface{}{"from": "n-1", "to": "n-2", "type": "WLAN", "state": "CONNECTED", "rate_rx": 650000.0, "rate_tx": 600000.0}
+	if !reflect.DeepEqual(out.Links[0], want) {
+		t.Errorf("link 0 = %v, want %v", out.Links[0], want)
+	}
+}
+
+func TestMeshGraphDOT(t *testing.T) {
+	want := `graph mesh {
+  "n-1" [label="fritz.box\nFRITZ!Box 7590", shape=box];
+  "n-2" [label="repeater\nFRITZ!Repeater 2400", shape=box];
+  "n-3" [label="laptop", shape=ellipse];
+  "n-4" [label="printer", shape=ellipse];
+  "n-5" [label="laptop", shape=ellipse];
+  "n-6" [label="repeater 2\nFRITZ!Repeater 1200", shape=box];
+  "n-1" -- "n-2" [label="WLAN\n650/600 Mbit/s", style=solid];
+  "n-2" -- "n-3" [label="WLAN\n72/65 Mbit/s", style=solid];
+  "n-1" -- "n-4" [label="LAN", style=dashed];
+  "n-2" -- "n-5" [label="WLAN\n72/65 Mbit/s", style=solid];
+  "n-1" -- "n-6" [label="WLAN\n200/100 Mbit/s", style=solid];
+  "n-2" -- "n-6" [label="LAN\n1000/1000 Mbit/s", style=solid];
+}
+`
+	if got := readMeshGraph(t).DOT(); got != want {
+		t.Errorf("DOT() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,61 @@
+package fritz
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MobileService is the TR-064 service of the LTE/5G modem. GetInfoEx has
+// RSRP, RSRQ, SINR, RSSI, band and cell id, GetAccessTechnology whether
+// LTE or 5G is used. The mobile status pages of the web interface show
+// nothing of that beyond TR-064 and aren't read; which WAN is active is
+// not reported by either and follows from the WAN link state.
+const MobileService = "urn:dslforum-org:service:X_AVM-DE_WANMobileConnection:1"
+
+// MobileInfo is the state of the mobile connection. Signal values the
+// modem doesn't report, e.g. SINR on older firmware, are nil.
+type MobileInfo struct {
+	Enabled    bool
+	Status     string
+	Technology string
+	Band       string
+	CellID     string
+	Operator   string
+	RSRP       *float64
+	RSRQ       *float64
+	SINR       *float64
+	RSSI       *float64
+}
+
+// DecodeMobileInfo builds MobileInfo from the output arguments of the
+// GetInfo, GetInfoEx and GetAccessTechnology actions of MobileService, as
+// documented in AVM's x_wanmobileconn description. infoEx and technology
+// are nil if the firmware lacks the action.
+func DecodeMobileInfo(info map[string]string, infoEx map[string]string, technology map[string]string) *MobileInfo {
+	return &MobileInfo{
+		Enabled:    info["NewEnabled"] == "1",
+		Status:     info["NewStatus"],
+		Technology: technology["NewCurrentAccessTechnology"],
+		Band:       infoEx["NewCurrentBand"],
+		CellID:     infoEx["NewCellid"],
+		Operator:   infoEx["NewOperator"],
+		RSRP:       floatArg(infoEx["NewSignalRSRP0"]),
+		RSRQ:       floatArg(infoEx["NewSignalRSRQ0"]),
+		SINR:       floatArg(infoEx["NewSignalSINR0"]),
+		RSSI:       floatArg(infoEx["NewSignalRSSI0"]),
+	}
+}
+
+// Connected reports whether the modem has an established data connection.
+func (m *MobileInfo) Connected() bool {
+	return strings.EqualFold(m.Status, "Connected") || strings.EqualFold(m.Status, "Online")
+}
+
+// floatArg parses an optional numeric argument, nil if it is missing.
+func floatArg(v string) *float64 {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
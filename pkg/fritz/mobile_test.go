@@ -0,0 +1,55 @@
+package fritz
+
+import (
+	"testing"
+)
+
+func TestDecodeMobileInfo(t *testing.T) {
+	// GetInfo, GetInfoEx and GetAccessTechnology of a 6850 LTE
+	info := map[string]string{
+		"NewEnabled":         "1",
+		"NewStatus":          "Connected",
+		"NewPINFailureCount": "3",
+		"NewPUKFailureCount": "10",
+	}
+	infoEx := map[string]string{
+		"NewStatus":      "Connected",
+		"NewCellid":      "26817035",
+		"NewCurrentBand": "20",
+		"NewOperator":    "Telekom.de",
+		"NewSignalRSRP0": "-97",
+		"NewSignalRSRP1": "-101",
+		"NewSignalRSRQ0": "-11",
+		"NewSignalRSRQ1": "-13",
+		"NewSignalSINR0": "7",
+		"NewSignalRSSI0": "-68",
+	}
+	technology := map[string]string{
+		"NewAccessTechnology":         "AUTO",
+		"NewCurrentAccessTechnology":  "LTE",
+		"NewPossibleAccessTechnology": "AUTO,UMTS,LTE",
+	}
+	m := DecodeMobileInfo(info, infoEx, technology)
+	if !m.Enabled || !m.Connected() || m.Technology != "LTE" || m.Band != "20" || m.CellID != "26817035" || m.Operator != "Telekom.de" {
+		t.Errorf("DecodeMobileInfo() = %+v", m)
+	}
+	for name, tc := range map[string]struct {
+		got  *float64
+		want float64
+	}{
+		"RSRP": {m.RSRP, -97},
+		"RSRQ": {m.RSRQ, -11},
+		"SINR": {m.SINR, 7},
+		"RSSI": {m.RSSI, -68},
+	} {
+		if tc.got == nil || *tc.got != tc.want {
+			t.Errorf("%s = %v, want %v", name, tc.got, tc.want)
+		}
+	}
+
+	// firmware without GetInfoEx and GetAccessTechnology
+	m = DecodeMobileInfo(map[string]string{"NewEnabled": "1", "NewStatus": "Disconnected"}, nil, nil)
+	if m.Connected() || m.RSRP != nil || m.Technology != "" {
+		t.Errorf("DecodeMobileInfo() without optional actions = %+v", m)
+	}
+}
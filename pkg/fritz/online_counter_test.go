@@ -0,0 +1,61 @@
+package fritz
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecodeOnlineCounter(t *testing.T) {
+	want := []OnlineCounter{
+		{Period: "today", OnlineTime: 2*time.Hour + 53*time.Minute, TotalBytes: 1523e6, SentBytes: 120e6, ReceivedBytes: 1403e6, Connections: 1},
+		{Period: "yesterday", OnlineTime: 24 * time.Hour, TotalBytes: 8250.5e6, SentBytes: 950e6, ReceivedBytes: 7300.5e6, Connections: 1},
+		{Period: "this_week", OnlineTime: 50*time.Hour + 53*time.Minute, TotalBytes: 14000e6, SentBytes: 1500e6, ReceivedBytes: 12500e6, Connections: 3},
+		{Period: "this_month", OnlineTime: 410*time.Hour + 5*time.Minute, TotalBytes: 120000e6, SentBytes: 10000e6, ReceivedBytes: 110000e6, Connections: 18},
+		{Period: "last_month", OnlineTime: 744 * time.Hour, TotalBytes: 250000e6, SentBytes: 20000e6, ReceivedBytes: 230000e6, Connections: 31},
+	}
+	for _, tc := range []struct {
+		name string
+		body string
+	}{
+		{"german", "sep=;\r\n" +
+			"Zeitraum;Online-Zeit (hh:mm);Datenvolumen gesamt(MB);Datenvolumen gesendet(MB);Datenvolumen empfangen(MB);Verbindungen\r\n" +
+			"Heute;02:53;1523;120;1403;1\r\n" +
+			"Gestern;24:00;8250,5;950;7300,5;1\r\n" +
+			"Aktuelle Woche;50:53;14000;1500;12500;3\r\n" +
+			"Aktueller Monat;410:05;120000;10000;110000;18\r\n" +
+			"Vormonat;744:00;250000;20000;230000;31\r\n"},
+		// the periods are told apart by their order, not their names
+		{"english", "sep=;\n" +
+			"Period;Online time (hh:mm);Total data volume(MB);Data volume sent(MB);Data volume received(MB);Connections\n" +
+			"Today;02:53;1523;120;1403;1\n" +
+			"Yesterday;24:00;8250.5;950;7300.5;1\n" +
+			"Current week;50:53;14000;1500;12500;3\n" +
+			"Current month;410:05;120000;10000;110000;18\n" +
+			"Previous month;744:00;250000;20000;230000;31\n" +
+			"Total;1231:51;393774;32570;361204;54\n"},
+	} {
+		got, err := DecodeOnlineCounter(tc.body)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: DecodeOnlineCounter() = %+v, want %+v", tc.name, got, want)
+		}
+	}
+
+	for _, tc := range []struct {
+		name string
+		body string
+		err  string
+	}{
+		{"no rows", "sep=;\nZeitraum;Online-Zeit (hh:mm);Datenvolumen gesamt(MB);Datenvolumen gesendet(MB);Datenvolumen empfangen(MB);Verbindungen\n", "no online counter rows found"},
+		{"invalid volume", "Heute;02:53;1523;n/a;1403;1\n", `online counter today: strconv.ParseFloat: parsing "n/a": invalid syntax`},
+	} {
+		_, err := DecodeOnlineCounter(tc.body)
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("%s: DecodeOnlineCounter() = %v, want %q", tc.name, err, tc.err)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package fritz
 
 import (
+	"fmt"
+
 	"github.com/Jeffail/gabs/v2"
 )
 
@@ -24,16 +26,17 @@ func DecodeOverViewData(body string) (Overview, error) {
 	jsonParsed, err := gabs.ParseJSON([]byte(body))
 	if err != nil {
 		return ov, err
-	} else {
-		fos := FritzOS{}
-		fritzos := jsonParsed.Path("data.fritzos")
-		fos.ProductName = fritzos.Path("Productname").Data().(string)
-		fos.Version = fritzos.Path("nspver").Data().(string)
-		ov.FritzOS = fos
-		internet := Internet{}
-		inet := jsonParsed.Path("data.internet")
-		internet.Txt = inet.Path("txt").Children()[0].Data().(string)
-		ov.Internet = internet
+	}
+	fritzos := jsonParsed.Path("data.fritzos")
+	productName, _ := fritzos.Path("Productname").Data().(string)
+	version, _ := fritzos.Path("nspver").Data().(string)
+	if productName == "" && version == "" {
+		return ov, fmt.Errorf("overview without fritzos: %w", ErrInvalidResponse)
+	}
+	ov.FritzOS = FritzOS{ProductName: productName, Version: version}
+	// the internet state is missing on boxes without WAN, e.g. repeaters
+	if txt := jsonParsed.Path("data.internet.txt").Children(); len(txt) > 0 {
+		ov.Internet.Txt, _ = txt[0].Data().(string)
 	}
 	return ov, nil
 }
@@ -0,0 +1,600 @@
+package fritz
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"github.com/ndecker/fritzbox_exporter/fritzbox_upnp"
+)
+
+// EmptySID is handed out by the FRITZ!Box for invalid or missing sessions.
+const EmptySID = "0000000000000000"
+
+var (
+	// ErrLoginFailed is returned when the box refuses the given credentials.
+	ErrLoginFailed = errors.New("Login failed")
+	// ErrNotSupported is returned for TR-064 services or actions the box
+	// doesn't offer, e.g. WAN services on a repeater.
+	ErrNotSupported = errors.New("not supported by this box")
+	// ErrInvalidResponse is returned for answers of the box that lack
+	// the expected content.
+	ErrInvalidResponse = errors.New("invalid response")
+
+	hostPattern = regexp.MustCompile("http.*://([^/:]*).*")
+)
+
+// Session handles the SID of the FRITZ!Box web interface and the TR-064
+// service description. Every request made through a Session is retried once
+// with a fresh login if the box reports the SID as expired.
+type Session struct {
+	BaseURL  string
+	Username string
+	Password string
+
+	client *http.Client
+
+	// loginMu serializes logins, validations and logouts. mu only guards
+	// the fields below and is never held during a request, so concurrent
+	// collectors don't wait for each other.
+	loginMu      sync.Mutex
+	mu           sync.Mutex
+	sid          string
+	rights       Rights
+	backoff      Backoff
+	blockedUntil time.Time
+	logins       map[string]uint64
+	digest       *digestChallenge
+	localIP      string
+
+	// servicesMu guards the TR-064 service description and serializes loading it
+	servicesMu       sync.Mutex
+	upnpServicesRoot *fritzbox_upnp.Root
+	servicesLoaded   time.Time
+}
+
+// servicesReloadInterval limits how often the service description is
+// reloaded for a missing service or action. Boxes without e.g. DSL would
+// otherwise reload it on every update.
+const servicesReloadInterval = 10 * time.Minute
+
+// NewSession creates a Session for the box reachable at baseURL. No request is
+// made before the first Login or Query.
+func NewSession(baseURL, username, password string) *Session {
+	s := &Session{
+		BaseURL:  strings.TrimSuffix(baseURL, "/"),
+		Username: username,
+		Password: password,
+		sid:      EmptySID,
+		backoff:  DefaultLoginBackoff(),
+		logins:   make(map[string]uint64),
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err == nil {
+			s.setLocalAddr(conn.LocalAddr())
+		}
+		return conn, err
+	}
+	s.client = &http.Client{
+		Timeout:   time.Duration(10 * time.Second),
+		Transport: transport,
+	}
+	return s
+}
+
+func (s *Session) setLocalAddr(addr net.Addr) {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.localIP = tcp.IP.String()
+}
+
+// LocalIP returns the address the last connection to the box was made from,
+// which the box logs for logins. It is empty before the first request.
+func (s *Session) LocalIP() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.localIP
+}
+
+// SID returns the current session id, EmptySID if not logged in.
+func (s *Session) SID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sid
+}
+
+// Rights returns the permissions granted to the current session.
+func (s *Session) Rights() Rights {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rights
+}
+
+// LoggedIn reports whether a SID has been obtained, it does not check its validity.
+func (s *Session) LoggedIn() bool {
+	return s.SID() != EmptySID
+}
+
+// BlockedUntil returns the time before which no further login is attempted.
+func (s *Session) BlockedUntil() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.blockedUntil
+}
+
+// LoginCounts returns the number of login attempts by result: success,
+// failed (credentials refused), blocked (not attempted) and error. TR-064
+// calls whose credentials are refused count as failed.
+func (s *Session) LoginCounts() map[string]uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[string]uint64, len(s.logins))
+	for result, count := range s.logins {
+		counts[result] = count
+	}
+	return counts
+}
+
+// Login performs the challenge-response login and stores the obtained SID.
+// After a failure no request is sent to the box until the BlockTime reported
+// by the box and the login backoff have passed, a LoginBlockedError is
+// returned instead.
+func (s *Session) Login() error {
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	return s.login()
+}
+
+// login must be called with loginMu held.
+func (s *Session) login() error {
+	err := s.doLogin()
+	var blocked *LoginBlockedError
+	result := "error"
+	switch {
+	case err == nil:
+		result = "success"
+	case errors.Is(err, ErrLoginFailed):
+		result = "failed"
+	case errors.As(err, &blocked):
+		result = "blocked"
+	}
+	s.countLogin(result)
+	return err
+}
+
+func (s *Session) countLogin(result string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logins[result]++
+}
+
+func (s *Session) doLogin() error {
+	if until := s.BlockedUntil(); time.Now().Before(until) {
+		return &LoginBlockedError{Until: until}
+	}
+	// version=2 makes FRITZ!OS 7.24+ hand out a PBKDF2 challenge, older firmware ignores it
+	c, err := s.sessionInfo("version=2")
+	if err != nil {
+		s.loginFailed(0)
+		return err
+	}
+	if c.BlockTime > 0 {
+		// answering now would only extend the block
+		return &LoginBlockedError{Until: s.loginFailed(c.BlockTime)}
+	}
+	if c.SID != EmptySID {
+		s.setSID(c.SID, c.Rights)
+		return nil
+	}
+
+	var query string
+	if strings.HasPrefix(c.Challenge, "2$") {
+		responseCode, err := GetPBKDF2Response(c.Challenge, s.Password)
+		if err != nil {
+			s.loginFailed(0)
+			return err
+		}
+		query = "version=2&username=" + url.QueryEscape(s.Username) + "&response=" + responseCode
+	} else {
+		// box does not support PBKDF2 challenges, fall back to MD5
+		//cpstr := strings.ToLower(c.Challenge + "-" + s.Password)
+		cpstr := c.Challenge + "-" + s.Password
+		md5sum, err := GetMD5Hash(cpstr)
+		if err != nil {
+			s.loginFailed(0)
+			return err
+		}
+		responseCode := c.Challenge + "-" + md5sum
+		query = "user=" + url.QueryEscape(s.Username) + "&response=" + responseCode
+	}
+	c, err = s.sessionInfo(query)
+	if err != nil {
+		s.loginFailed(0)
+		return err
+	}
+	if c.SID == EmptySID {
+		s.setSID(EmptySID, nil)
+		s.loginFailed(c.BlockTime)
+		return ErrLoginFailed
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sid = c.SID
+	s.rights = c.Rights
+	s.backoff.Reset()
+	s.blockedUntil = time.Time{}
+	return nil
+}
+
+func (s *Session) setSID(sid string, rights Rights) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sid = sid
+	s.rights = rights
+}
+
+// loginFailed blocks further attempts for the next backoff step, or for the
+// BlockTime in seconds demanded by the box if that is longer. It returns
+// the end of the block.
+func (s *Session) loginFailed(blockTime int) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wait := s.backoff.Next()
+	if box := time.Duration(blockTime) * time.Second; box > wait {
+		wait = box
+	}
+	s.blockedUntil = time.Now().Add(wait)
+	return s.blockedUntil
+}
+
+// Validate asks the box whether the current SID is still accepted.
+func (s *Session) Validate() (bool, error) {
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	return s.validate()
+}
+
+// validate must be called with loginMu held.
+func (s *Session) validate() (bool, error) {
+	sid := s.SID()
+	if sid == EmptySID {
+		return false, nil
+	}
+	c, err := s.sessionInfo("version=2&sid=" + sid)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.SID != sid {
+		s.sid = EmptySID
+		s.rights = nil
+		return false, nil
+	}
+	s.rights = c.Rights
+	return true, nil
+}
+
+// Logout invalidates the current SID on the box.
+func (s *Session) Logout() error {
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	sid := s.SID()
+	if sid == EmptySID {
+		return nil
+	}
+	_, err := s.sessionInfo("version=2&logout=1&sid=" + sid)
+	s.setSID(EmptySID, nil)
+	return err
+}
+
+func (s *Session) sessionInfo(query string) (*LoginChallenge, error) {
+	resp, err := s.client.Get(s.BaseURL + "/login_sid.lua?" + query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	// expected: <?xml version="1.0" encoding="utf-8"?><SessionInfo><SID>0000000000000000</SID><Challenge>abababababa</Challenge><BlockTime>0</BlockTime><Rights></Rights></SessionInfo>
+	c := &LoginChallenge{}
+	err = xml.Unmarshal(body, c)
+	if err != nil {
+		return nil, fmt.Errorf("parsing session info: %w", err)
+	}
+	return c, nil
+}
+
+// Query requests path from the web interface, either as GET with the given
+// options as query string or, if urlData is set, with urlData as form body.
+// An expired session is renewed and the request repeated once.
+func (s *Session) Query(path string, options string, method string, urlData url.Values) (string, error) {
+	sid, err := s.ensureSID()
+	if err != nil {
+		return "", err
+	}
+	body, suspect, err := s.query(sid, path, options, method, urlData)
+	if err != nil || !suspect {
+		return body, err
+	}
+	sid, renewed, err := s.renew(sid)
+	if err != nil {
+		return "", err
+	}
+	if !renewed {
+		return body, nil
+	}
+	body, _, err = s.query(sid, path, options, method, urlData)
+	return body, err
+}
+
+// ensureSID returns the current SID, logging in first if there is none.
+func (s *Session) ensureSID() (string, error) {
+	if sid := s.SID(); sid != EmptySID {
+		return sid, nil
+	}
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	// another request may have logged in meanwhile
+	if sid := s.SID(); sid != EmptySID {
+		return sid, nil
+	}
+	if err := s.login(); err != nil {
+		return "", err
+	}
+	return s.SID(), nil
+}
+
+// renew asks login_sid.lua whether sid is still valid after an answer
+// that may come from an expired session, and logs in again if it isn't.
+// renewed is false if sid is valid, the answer is genuine then.
+func (s *Session) renew(sid string) (string, bool, error) {
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	current := s.SID()
+	if current != sid && current != EmptySID {
+		// renewed by a concurrent request
+		return current, true, nil
+	}
+	if current == sid {
+		valid, err := s.validate()
+		if err != nil {
+			return "", false, err
+		}
+		if valid {
+			return sid, false, nil
+		}
+	}
+	if err := s.login(); err != nil {
+		return "", false, err
+	}
+	return s.SID(), true, nil
+}
+
+func (s *Session) query(sid string, path string, options string, method string, urlData url.Values) (string, bool, error) {
+	if options == "" {
+		options = "0=0"
+	}
+	var body io.Reader
+	var uri string
+	if urlData != nil {
+		urlData.Set("sid", sid)
+		body = strings.NewReader(urlData.Encode())
+		uri = s.BaseURL + "/" + path
+	} else {
+		uri = s.BaseURL + "/" + path + "?sid=" + sid + "&" + options
+	}
+
+	request, err := http.NewRequest(method, uri, body)
+	if err != nil {
+		return "", false, err
+	}
+	request.Header.Set("Content-type", "application/x-www-form-urlencoded")
+	resp, err := s.client.Do(request)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", false, err
+	}
+	return string(respBody), mayBeExpired(resp), nil
+}
+
+// mayBeExpired tells answers the box may give for an unknown SID: query.lua
+// and the webservices respond with 403, data.lua and the csv pages with the
+// html login page. None of the pages queried is html otherwise. Whether the
+// SID really expired is checked with login_sid.lua.
+func mayBeExpired(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	return strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html")
+}
+
+// Call executes a TR-064 action without authentication. The service
+// description is loaded on first use and reloaded if it lacks the service
+// or action, e.g. after a firmware update. Actions that need
+// authentication are called with CallAction.
+func (s *Session) Call(serviceType string, actionName string) (fritzbox_upnp.Result, error) {
+	_, action, err := s.lookupAction(serviceType, actionName)
+	if err != nil {
+		return nil, err
+	}
+	return action.Call()
+}
+
+// lookupAction finds the action in the service description. A missing
+// service or action reloads the description, at most once per
+// servicesReloadInterval.
+func (s *Session) lookupAction(serviceType string, actionName string) (*fritzbox_upnp.Service, *fritzbox_upnp.Action, error) {
+	s.servicesMu.Lock()
+	defer s.servicesMu.Unlock()
+	if s.upnpServicesRoot == nil {
+		if err := s.loadServices(); err != nil {
+			return nil, nil, err
+		}
+	}
+	service, action, err := s.findAction(serviceType, actionName)
+	if err == nil || time.Since(s.servicesLoaded) < servicesReloadInterval {
+		return service, action, err
+	}
+	if err := s.loadServices(); err != nil {
+		return nil, nil, err
+	}
+	return s.findAction(serviceType, actionName)
+}
+
+func (s *Session) findAction(serviceType string, actionName string) (*fritzbox_upnp.Service, *fritzbox_upnp.Action, error) {
+	service, ok := s.upnpServicesRoot.Services[serviceType]
+	if !ok {
+		return nil, nil, fmt.Errorf("service %s: %w", serviceType, ErrNotSupported)
+	}
+	action, ok := service.Actions[actionName]
+	if !ok {
+		return nil, nil, fmt.Errorf("action %s: %w", actionName, ErrNotSupported)
+	}
+	return service, action, nil
+}
+
+// Services returns the loaded TR-064 service description.
+func (s *Session) Services() (*fritzbox_upnp.Root, error) {
+	s.servicesMu.Lock()
+	defer s.servicesMu.Unlock()
+	if s.upnpServicesRoot == nil {
+		if err := s.loadServices(); err != nil {
+			return nil, err
+		}
+	}
+	return s.upnpServicesRoot, nil
+}
+
+// loadServices must be called with servicesMu held.
+func (s *Session) loadServices() error {
+	device := hostPattern.FindStringSubmatch(s.BaseURL)
+	if device == nil {
+		return fmt.Errorf("cannot determine host of %s", s.BaseURL)
+	}
+	root, err := fritzbox_upnp.LoadServices(device[1], 49000)
+	if err != nil {
+		return err
+	}
+	s.upnpServicesRoot = root
+	s.servicesLoaded = time.Now()
+	return nil
+}
+
+// GetPBKDF2Response computes the answer to a version 2 login challenge of the
+// form "2$<iter1>$<salt1>$<iter2>$<salt2>" as described by AVM:
+// hash1 = PBKDF2-HMAC-SHA256(password, salt1, iter1), hash2 = PBKDF2-HMAC-SHA256(hash1, salt2, iter2)
+// and the response is "<salt2>$<hash2>".
+func GetPBKDF2Response(challenge string, password string) (string, error) {
+	parts := strings.Split(challenge, "$")
+	if len(parts) != 5 || parts[0] != "2" {
+		return "", fmt.Errorf("unsupported challenge %q", challenge)
+	}
+	iter1, err := challengeIterations(parts[1])
+	if err != nil {
+		return "", err
+	}
+	salt1, err := challengeSalt(parts[2])
+	if err != nil {
+		return "", err
+	}
+	iter2, err := challengeIterations(parts[3])
+	if err != nil {
+		return "", err
+	}
+	salt2, err := challengeSalt(parts[4])
+	if err != nil {
+		return "", err
+	}
+	hash1 := pbkdf2SHA256([]byte(password), salt1, iter1)
+	hash2 := pbkdf2SHA256(hash1, salt2, iter2)
+	return parts[4] + "$" + hex.EncodeToString(hash2), nil
+}
+
+func challengeIterations(s string) (int, error) {
+	iter, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid iteration count in challenge: %w", err)
+	}
+	if iter < 1 {
+		return 0, fmt.Errorf("invalid iteration count %d in challenge", iter)
+	}
+	return iter, nil
+}
+
+func challengeSalt(s string) ([]byte, error) {
+	salt, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt in challenge: %w", err)
+	}
+	if len(salt) == 0 {
+		return nil, fmt.Errorf("empty salt in challenge")
+	}
+	return salt, nil
+}
+
+// pbkdf2SHA256 derives a single SHA256 sized block (RFC 8018), which is all
+// the FRITZ!Box challenge needs.
+func pbkdf2SHA256(password, salt []byte, iter int) []byte {
+	prf := hmac.New(sha256.New, password)
+	prf.Write(salt)
+	prf.Write([]byte{0, 0, 0, 1})
+	u := prf.Sum(nil)
+	key := make([]byte, len(u))
+	copy(key, u)
+	for i := 1; i < iter; i++ {
+		prf.Reset()
+		prf.Write(u)
+		u = prf.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key
+}
+
+// GetMD5Hash computes the legacy challenge response hash over the UTF-16LE
+// encoded text.
+func GetMD5Hash(text string) (string, error) {
+	hasher := md5.New()
+	codes := utf16.Encode([]rune(text))
+	b := make([]byte, len(codes)*2)
+	for i, r := range codes {
+		b[i*2] = byte(r)
+		b[i*2+1] = byte(r >> 8)
+	}
+	_, err := hasher.Write(b)
+	if err != nil {
+		return "", fmt.Errorf("hashing challenge: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
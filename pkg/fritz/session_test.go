@@ -0,0 +1,126 @@
+package fritz
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestGetPBKDF2Response(t *testing.T) {
+	// example from AVM's "Session-IDs im FRITZ!Box Webinterface"
+	got, err := GetPBKDF2Response("2$10000$5A1711$2000$5A1722", "1example!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "5A1722$1798a1672bca7c6463d6b245f82b53703b0f50813401b03e4045a5861e689adb"
+	if got != want {
+		t.Errorf("GetPBKDF2Response() = %q, want %q", got, want)
+	}
+}
+
+func TestGetPBKDF2ResponseInvalidChallenge(t *testing.T) {
+	for _, challenge := range []string{
+		"",
+		"1234567z",
+		"2$10000$5A1711$2000",
+		"3$10000$5A1711$2000$5A1722",
+		"2$x$5A1711$2000$5A1722",
+		"2$0$5A1711$2000$5A1722",
+		"2$10000$5A1711$-1$5A1722",
+		"2$10000$5Z1711$2000$5A1722",
+		"2$10000$5A1711$2000$5A172",
+		"2$10000$$2000$5A1722",
+	} {
+		if got, err := GetPBKDF2Response(challenge, "1example!"); err == nil {
+			t.Errorf("GetPBKDF2Response(%q) = %q, want error", challenge, got)
+		}
+	}
+}
+
+func TestGetMD5Hash(t *testing.T) {
+	// example from AVM's "Session-IDs im FRITZ!Box Webinterface", the
+	// umlaut checks the UTF-16LE encoding
+	got, err := GetMD5Hash("1234567z-äbc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "9e224a41eeefa284df7bb0f26c2913e2"
+	if got != want {
+		t.Errorf("GetMD5Hash() = %q, want %q", got, want)
+	}
+}
+
+// boxStub answers login_sid.lua like a box accepting any response and
+// data.lua with the html login page for SIDs it doesn't know.
+type boxStub struct {
+	mu       sync.Mutex
+	sid      string
+	logins   int
+	validate int
+}
+
+func (b *boxStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch r.URL.Path {
+	case "/login_sid.lua":
+		sid := EmptySID
+		switch {
+		case r.FormValue("response") != "":
+			b.logins++
+			b.sid = fmt.Sprintf("%016d", b.logins)
+			sid = b.sid
+		case r.FormValue("sid") != "":
+			b.validate++
+			if r.FormValue("sid") == b.sid {
+				sid = b.sid
+			}
+		}
+		fmt.Fprintf(w, "<SessionInfo><SID>%s</SID><Challenge>1234567z</Challenge><BlockTime>0</BlockTime><Rights></Rights></SessionInfo>", sid)
+	case "/data.lua":
+		if r.FormValue("sid") != b.sid {
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html>login</html>")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{}}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestQueryRenewsExpiredSession(t *testing.T) {
+	box := &boxStub{}
+	server := httptest.NewServer(box)
+	defer server.Close()
+	s := NewSession(server.URL, "user", "password")
+
+	body, err := s.Query("data.lua", "", http.MethodGet, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != `{"data":{}}` || box.logins != 1 {
+		t.Fatalf("first query: body %q after %d logins", body, box.logins)
+	}
+
+	// the box forgets the session, e.g. after a reboot
+	box.mu.Lock()
+	box.sid = "expired"
+	box.mu.Unlock()
+	body, err = s.Query("data.lua", "", http.MethodGet, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != `{"data":{}}` {
+		t.Errorf("query after expiry: body %q", body)
+	}
+	if box.validate != 1 || box.logins != 2 {
+		t.Errorf("query after expiry: %d validations and %d logins, want 1 and 2", box.validate, box.logins)
+	}
+	if s.SID() != "0000000000000002" {
+		t.Errorf("SID() = %q", s.SID())
+	}
+}
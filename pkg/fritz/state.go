@@ -0,0 +1,87 @@
+package fritz
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// sessionState is what gets persisted between exporter restarts. URL and user
+// are stored to avoid reusing a SID for a different box or account.
+type sessionState struct {
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	SID      string `json:"sid"`
+}
+
+// LoadState reads a SID written by a previous run from path and validates it
+// with the box. It returns true if the stored session could be reused, a
+// missing state file is not an error.
+func (s *Session) LoadState(path string) (bool, error) {
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var state sessionState
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return false, err
+	}
+	if state.URL != s.BaseURL || state.Username != s.Username || state.SID == "" {
+		return false, nil
+	}
+
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	s.setSID(state.SID, nil)
+	return s.validate()
+}
+
+// SaveState writes the current SID to path, readable by the owner only since
+// the SID grants the same access as the password until it expires.
+func (s *Session) SaveState(path string) error {
+	state := sessionState{
+		URL:      s.BaseURL,
+		Username: s.Username,
+		SID:      s.SID(),
+	}
+	if state.SID == EmptySID {
+		return RemoveState(path)
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first, so a crash never leaves a truncated state
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// RemoveState deletes the state file at path, if any.
+func RemoveState(path string) error {
+	err := os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -2,6 +2,9 @@ package fritz
 
 import (
 	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
 )
 
 type LoginChallenge struct {
@@ -9,4 +12,61 @@ type LoginChallenge struct {
 	SID       string   `xml:"SID"`
 	Challenge string   `xml:"Challenge"`
 	BlockTime int      `xml:"BlockTime"`
+	Rights    Rights   `xml:"Rights"`
+}
+
+// Rights maps the permission names of a session (e.g. BoxAdmin, Phone, Dial,
+// NAS, HomeAuto, App) to their access level: 0 none, 1 read, 2 read/write.
+type Rights map[string]int
+
+// UnmarshalXML decodes the flat <Name>X</Name><Access>N</Access> sequence
+// used by login_sid.lua.
+func (r *Rights) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var raw struct {
+		Items []struct {
+			XMLName xml.Name
+			Value   string `xml:",chardata"`
+		} `xml:",any"`
+	}
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		return err
+	}
+	rights := Rights{}
+	var name string
+	for _, item := range raw.Items {
+		switch item.XMLName.Local {
+		case "Name":
+			name = item.Value
+		case "Access":
+			access, err := strconv.Atoi(item.Value)
+			if err != nil {
+				return err
+			}
+			rights[name] = access
+		}
+	}
+	*r = rights
+	return nil
+}
+
+// CanRead reports whether the session has at least read access for name.
+func (r Rights) CanRead(name string) bool {
+	return r[name] >= 1
+}
+
+// LenientFloat is a number the data.lua pages send either as JSON number or
+// as string, an empty string or null leaves it unchanged.
+type LenientFloat float64
+
+func (f *LenientFloat) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %s: %w", data, err)
+	}
+	*f = LenientFloat(v)
+	return nil
 }
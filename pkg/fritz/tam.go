@@ -0,0 +1,75 @@
+package fritz
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// TAMService is the TR-064 service of the answering machines (telephone
+// answering machine, TAM) of the box.
+const TAMService = "urn:dslforum-org:service:X_AVM-DE_TAM:1"
+
+// TAM is a configured answering machine and its messages.
+type TAM struct {
+	Index       string
+	Name        string
+	Enabled     bool
+	Messages    int
+	NewMessages int
+}
+
+type tamList struct {
+	XMLName xml.Name `xml:"List"`
+	Items   []struct {
+		Index   string `xml:"Index"`
+		Display string `xml:"Display"`
+		Enable  string `xml:"Enable"`
+		Name    string `xml:"Name"`
+	} `xml:"Item"`
+}
+
+// DecodeTAMList decodes NewTAMList of the GetList action of TAMService.
+// The list has an item for every possible answering machine, those not
+// set up in the web interface aren't displayed and are left out.
+func DecodeTAMList(list string) ([]TAM, error) {
+	l := tamList{}
+	err := xml.Unmarshal([]byte(list), &l)
+	if err != nil {
+		return nil, fmt.Errorf("decoding TAM list: %v: %w", err, ErrInvalidResponse)
+	}
+	var tams []TAM
+	for _, item := range l.Items {
+		if strings.TrimSpace(item.Display) != "1" {
+			continue
+		}
+		tams = append(tams, TAM{
+			Index:   strings.TrimSpace(item.Index),
+			Name:    item.Name,
+			Enabled: strings.TrimSpace(item.Enable) == "1",
+		})
+	}
+	return tams, nil
+}
+
+type tamMessageList struct {
+	Messages []struct {
+		New string `xml:"New"`
+	} `xml:"Message"`
+}
+
+// CountTAMMessages counts all and the unheard messages of the message list
+// the URL returned by GetMessageList points to.
+func CountTAMMessages(data []byte) (messages int, newMessages int, err error) {
+	l := tamMessageList{}
+	err = xml.Unmarshal(data, &l)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, m := range l.Messages {
+		if strings.TrimSpace(m.New) == "1" {
+			newMessages++
+		}
+	}
+	return len(l.Messages), newMessages, nil
+}
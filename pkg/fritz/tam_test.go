@@ -0,0 +1,56 @@
+package fritz
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeTAMList(t *testing.T) {
+	// NewTAMList of GetList, only the first two answering machines are set up
+	body, err := os.ReadFile("testdata/tamlist.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tams, err := DecodeTAMList(string(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TAM{
+		{Index: "0", Name: "Anrufbeantworter", Enabled: true},
+		{Index: "1", Name: "Urlaub"},
+	}
+	if !reflect.DeepEqual(tams, want) {
+		t.Errorf("DecodeTAMList() = %+v, want %+v", tams, want)
+	}
+
+	if _, err := DecodeTAMList("<List><Item>"); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("DecodeTAMList() of a truncated list = %v, want ErrInvalidResponse", err)
+	}
+}
+
+func TestCountTAMMessages(t *testing.T) {
+	body, err := os.ReadFile("testdata/tam_messages.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name        string
+		data        []byte
+		messages    int
+		newMessages int
+	}{
+		{"messages", body, 3, 2},
+		{"empty", []byte(`<?xml version="1.0" encoding="utf-8"?><Root></Root>`), 0, 0},
+	} {
+		messages, newMessages, err := CountTAMMessages(tc.data)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if messages != tc.messages || newMessages != tc.newMessages {
+			t.Errorf("%s: CountTAMMessages() = %d, %d, want %d, %d", tc.name, messages, newMessages, tc.messages, tc.newMessages)
+		}
+	}
+}
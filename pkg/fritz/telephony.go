@@ -0,0 +1,137 @@
+package fritz
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VoIPService is the TR-064 service with the SIP accounts of the box.
+const VoIPService = "urn:dslforum-org:service:X_VoIP:1"
+
+// OnTelService is the TR-064 service with the call list and phone book.
+const OnTelService = "urn:dslforum-org:service:X_AVM-DE_OnTel:1"
+
+// MaxVoIPAccounts is the number of SIP accounts a box can hold, the
+// account indexes in use may have gaps.
+const MaxVoIPAccounts = 20
+
+// VoIPNumber is a configured SIP account and its registration state.
+type VoIPNumber struct {
+	Index  int
+	Number string
+	// Status is e.g. registered, not registered or disabled
+	Status string
+}
+
+// Registered reports whether the account is registered with its registrar.
+func (n VoIPNumber) Registered() bool {
+	return strings.EqualFold(n.Status, "registered")
+}
+
+// DecodeVoIPNumber builds VoIPNumber from the output arguments of the
+// X_AVM-DE_GetVoIPAccount and X_AVM-DE_GetVoIPStatus actions of VoIPService.
+func DecodeVoIPNumber(index int, account map[string]string, status map[string]string) VoIPNumber {
+	return VoIPNumber{
+		Index:  index,
+		Number: account["NewVoIPNumber"],
+		Status: strings.TrimSpace(status["NewX_AVM-DE_VoIPStatus"]),
+	}
+}
+
+// Call is an entry of the call list.
+type Call struct {
+	ID int
+	// Type is incoming, missed, outgoing, blocked, active_incoming or
+	// active_outgoing
+	Type     string
+	Caller   string
+	Called   string
+	Device   string
+	Date     time.Time
+	Duration time.Duration
+}
+
+// Active reports whether the call is still going on, the box changes its
+// type once it's finished.
+func (c Call) Active() bool {
+	return strings.HasPrefix(c.Type, "active_")
+}
+
+var callTypes = map[string]string{
+	"1":  "incoming",
+	"2":  "missed",
+	"3":  "outgoing",
+	"9":  "active_incoming",
+	"10": "blocked",
+	"11": "active_outgoing",
+}
+
+type callList struct {
+	XMLName xml.Name `xml:"root"`
+	Calls   []struct {
+		ID       int    `xml:"Id"`
+		Type     string `xml:"Type"`
+		Caller   string `xml:"Caller"`
+		Called   string `xml:"Called"`
+		Device   string `xml:"Device"`
+		Date     string `xml:"Date"`
+		Duration string `xml:"Duration"`
+	} `xml:"Call"`
+}
+
+// DecodeCallList decodes the call list the URL returned by GetCallList
+// points to. Dates are in the local time of the exporter, as the box
+// doesn't tell its time zone.
+func DecodeCallList(data []byte) ([]Call, error) {
+	list := callList{}
+	err := xml.Unmarshal(data, &list)
+	if err != nil {
+		return nil, err
+	}
+	calls := make([]Call, 0, len(list.Calls))
+	for _, c := range list.Calls {
+		callType, ok := callTypes[strings.TrimSpace(c.Type)]
+		if !ok {
+			callType = "unknown"
+		}
+		duration, err := parseCallDuration(c.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", c.ID, err)
+		}
+		call := Call{
+			ID:       c.ID,
+			Type:     callType,
+			Caller:   c.Caller,
+			Called:   c.Called,
+			Device:   c.Device,
+			Duration: duration,
+		}
+		call.Date, _ = time.ParseInLocation("02.01.06 15:04", strings.TrimSpace(c.Date), time.Local)
+		calls = append(calls, call)
+	}
+	return calls, nil
+}
+
+// parseCallDuration parses durations given as h:mm.
+func parseCallDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("duration %q: %w", s, ErrInvalidResponse)
+	}
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("duration %q: %w", s, ErrInvalidResponse)
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("duration %q: %w", s, ErrInvalidResponse)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
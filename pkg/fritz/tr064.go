@@ -0,0 +1,308 @@
+package fritz
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tr064Port is where the box answers TR-064 requests without TLS.
+const tr064Port = 49000
+
+// SOAPError is the UPnP error returned by a TR-064 action.
+type SOAPError struct {
+	Action      string
+	Code        int
+	Description string
+}
+
+func (e *SOAPError) Error() string {
+	return fmt.Sprintf("TR-064 action %s failed: %d %s", e.Action, e.Code, e.Description)
+}
+
+// Is makes "Invalid Action" match ErrNotSupported, some boxes list actions
+// in their service description they don't implement.
+func (e *SOAPError) Is(target error) bool {
+	return target == ErrNotSupported && e.Code == 401
+}
+
+// CallAction invokes a TR-064 action with arguments and returns its output
+// arguments by name, e.g. "NewUpstreamCurrRate". Unlike Call it
+// authenticates with the session's credentials, which most actions of the
+// tr64desc.xml services require. Refused credentials block further calls
+// and logins with the login backoff, a LoginBlockedError is returned
+// meanwhile.
+func (s *Session) CallAction(serviceType string, actionName string, args map[string]string) (map[string]string, error) {
+	if until := s.BlockedUntil(); time.Now().Before(until) {
+		s.countLogin("blocked")
+		return nil, &LoginBlockedError{Until: until}
+	}
+	controlURL, err := s.controlURL(serviceType, actionName)
+	if err != nil {
+		return nil, err
+	}
+	body := soapEnvelope(serviceType, actionName, args)
+
+	resp, err := s.soapRequest(controlURL, serviceType, actionName, body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		// the nonce expired or there was none yet, answer the new challenge
+		challenge, err := parseDigestChallenge(resp.Header.Get("WWW-Authenticate"))
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		s.digest = challenge
+		s.mu.Unlock()
+		resp, err = s.soapRequest(controlURL, serviceType, actionName, body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		s.countLogin("failed")
+		s.loginFailed(0)
+		return nil, fmt.Errorf("TR-064 action %s: %w", actionName, ErrLoginFailed)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		fault := soapFault{}
+		if xml.Unmarshal(data, &fault) == nil && fault.Body.Fault.Detail.UPnPError.ErrorCode != 0 {
+			return nil, &SOAPError{
+				Action:      actionName,
+				Code:        fault.Body.Fault.Detail.UPnPError.ErrorCode,
+				Description: fault.Body.Fault.Detail.UPnPError.ErrorDescription,
+			}
+		}
+		return nil, fmt.Errorf("TR-064 action %s: unexpected status %s", actionName, resp.Status)
+	}
+	return decodeActionResponse(data, actionName)
+}
+
+// controlURL looks up where to send requests for the action.
+func (s *Session) controlURL(serviceType string, actionName string) (string, error) {
+	service, _, err := s.lookupAction(serviceType, actionName)
+	if err != nil {
+		return "", err
+	}
+	device := hostPattern.FindStringSubmatch(s.BaseURL)
+	if device == nil {
+		return "", fmt.Errorf("cannot determine host of %s", s.BaseURL)
+	}
+	return fmt.Sprintf("http://%s:%d%s", device[1], tr064Port, service.ControlUrl), nil
+}
+
+func (s *Session) soapRequest(controlURL string, serviceType string, actionName string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, controlURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", serviceType+"#"+actionName)
+	s.mu.Lock()
+	if s.digest != nil {
+		req.Header.Set("Authorization", s.digest.authorization(s.Username, s.Password, req.Method, req.URL.RequestURI()))
+	}
+	s.mu.Unlock()
+	return s.client.Do(req)
+}
+
+func soapEnvelope(serviceType string, actionName string, args map[string]string) []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	b.WriteString(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(&b, `<u:%s xmlns:u="%s">`, actionName, serviceType)
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "<%s>", name)
+		xml.EscapeText(&b, []byte(args[name]))
+		fmt.Fprintf(&b, "</%s>", name)
+	}
+	fmt.Fprintf(&b, "</u:%s></s:Body></s:Envelope>", actionName)
+	return b.Bytes()
+}
+
+// decodeActionResponse collects the output arguments from the
+// <u:ActionResponse> element of the SOAP body.
+func decodeActionResponse(data []byte, actionName string) (map[string]string, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	result := make(map[string]string)
+	inResponse := false
+	var name string
+	var value strings.Builder
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decoding TR-064 response of %s: %w", actionName, err)
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			if t.Name.Local == actionName+"Response" {
+				inResponse = true
+			} else if inResponse {
+				name = t.Name.Local
+				value.Reset()
+			}
+		case xml.CharData:
+			if name != "" {
+				value.Write(t)
+			}
+		case xml.EndElement:
+			if t.Name.Local == actionName+"Response" {
+				return result, nil
+			}
+			if name != "" {
+				result[name] = value.String()
+				name = ""
+			}
+		}
+	}
+	return nil, fmt.Errorf("TR-064 response of %s: %w", actionName, ErrInvalidResponse)
+}
+
+type soapFault struct {
+	Body struct {
+		Fault struct {
+			Detail struct {
+				UPnPError struct {
+					ErrorCode        int    `xml:"errorCode"`
+					ErrorDescription string `xml:"errorDescription"`
+				} `xml:"UPnPError"`
+			} `xml:"detail"`
+		} `xml:"Fault"`
+	} `xml:"Body"`
+}
+
+// digestChallenge is the HTTP digest challenge of the TR-064 server, it is
+// reused with an increasing nonce count until the box sends a new one.
+type digestChallenge struct {
+	realm  string
+	nonce  string
+	opaque string
+	qop    string
+	nc     int
+}
+
+func parseDigestChallenge(header string) (*digestChallenge, error) {
+	if !strings.HasPrefix(header, "Digest ") {
+		return nil, fmt.Errorf("unsupported TR-064 authentication %q", header)
+	}
+	c := &digestChallenge{}
+	for _, param := range splitDigestParams(strings.TrimPrefix(header, "Digest ")) {
+		i := strings.Index(param, "=")
+		if i < 0 {
+			continue
+		}
+		value := strings.Trim(strings.TrimSpace(param[i+1:]), `"`)
+		switch strings.ToLower(strings.TrimSpace(param[:i])) {
+		case "realm":
+			c.realm = value
+		case "nonce":
+			c.nonce = value
+		case "opaque":
+			c.opaque = value
+		case "qop":
+			// the box offers "auth" only, take it if it's in a list
+			for _, qop := range strings.Split(value, ",") {
+				if strings.TrimSpace(qop) == "auth" {
+					c.qop = "auth"
+				}
+			}
+		}
+	}
+	if c.nonce == "" {
+		return nil, fmt.Errorf("TR-064 digest challenge without nonce: %q", header)
+	}
+	return c, nil
+}
+
+// splitDigestParams splits at commas outside of quotes.
+func splitDigestParams(s string) []string {
+	var params []string
+	quoted := false
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			params = append(params, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(params, s[start:])
+}
+
+func (c *digestChallenge) authorization(username string, password string, method string, uri string) string {
+	c.nc++
+	ha1 := md5Hex(username + ":" + c.realm + ":" + password)
+	ha2 := md5Hex(method + ":" + uri)
+	header := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s"`, username, c.realm, c.nonce, uri)
+	if c.qop == "" {
+		header += fmt.Sprintf(`, response="%s"`, md5Hex(ha1+":"+c.nonce+":"+ha2))
+	} else {
+		nc := fmt.Sprintf("%08x", c.nc)
+		cnonce := newCnonce()
+		response := md5Hex(ha1 + ":" + c.nonce + ":" + nc + ":" + cnonce + ":" + c.qop + ":" + ha2)
+		header += fmt.Sprintf(`, qop=%s, nc=%s, cnonce="%s", response="%s"`, c.qop, nc, cnonce, response)
+	}
+	if c.opaque != "" {
+		header += fmt.Sprintf(`, opaque="%s"`, c.opaque)
+	}
+	return header
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func newCnonce() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// GetTR064 fetches a file from the TR-064 server, e.g. the mesh list whose
+// path (including a SID) is returned by an action.
+func (s *Session) GetTR064(path string) ([]byte, error) {
+	device := hostPattern.FindStringSubmatch(s.BaseURL)
+	if device == nil {
+		return nil, fmt.Errorf("cannot determine host of %s", s.BaseURL)
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	resp, err := s.client.Get(fmt.Sprintf("http://%s:%d%s", device[1], tr064Port, path))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: unexpected status %s", path, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
@@ -22,56 +22,41 @@ type TrafficMonitoringData struct {
 	UpstreamLowPriority     []float64 `json:"us_background_bps_curr"`
 	UpstreamGuest           []float64 `json:"guest_us_bps,omitempty"`
 	Mode                    string    `json:"mode"`
+	Name                    string    `json:"name"`
 }
 
-// codebeat:enable[TOO_MANY_IVARS]
-
-// BitsPerSecond returns a TrafficMonitoringData with metrics in units of bits/second.
-func (d TrafficMonitoringData) BitsPerSecond() TrafficMonitoringData {
-	return TrafficMonitoringData{
-		DownstreamInternet:      d.DownstreamInternet,
-		DownstreamMedia:         d.DownstreamMedia,
-		DownstreamGuest:         d.DownstreamGuest,
-		UpstreamRealtime:        d.UpstreamRealtime,
-		UpstreamHighPriority:    d.UpstreamHighPriority,
-		UpstreamDefaultPriority: d.UpstreamDefaultPriority,
-		UpstreamLowPriority:     d.UpstreamLowPriority,
-		UpstreamGuest:           d.UpstreamGuest,
-	}
+// trafficMonitoringResponse is the answer of data.lua for page=netMoni since
+// FRITZ!OS 7.57, with one sync group per WAN line, e.g. DSL and a LTE backup.
+// The series hold the newest sample first:
+//
+//	{"data":{"sync_groups":[{"name":"DSL","mode":"VDSL","ds_bps_max":13125000,
+//	"ds_bps_curr":[412305,10245120,9871360],"us_default_bps_curr":[1536,40960,38912],...}]}}
+type trafficMonitoringResponse struct {
+	Data struct {
+		SyncGroups []TrafficMonitoringData `json:"sync_groups"`
+	} `json:"data"`
 }
 
-// KiloBitsPerSecond returns a TrafficMonitoringData with metrics in units of kbits/second.
-func (d TrafficMonitoringData) KiloBitsPerSecond() TrafficMonitoringData {
-	return TrafficMonitoringData{
-		DownstreamInternet:      div(d.DownstreamInternet, 1024),
-		DownstreamMedia:         div(d.DownstreamMedia, 1024),
-		DownstreamGuest:         div(d.DownstreamGuest, 1024),
-		UpstreamRealtime:        div(d.UpstreamRealtime, 1024),
-		UpstreamHighPriority:    div(d.UpstreamHighPriority, 1024),
-		UpstreamDefaultPriority: div(d.UpstreamDefaultPriority, 1024),
-		UpstreamLowPriority:     div(d.UpstreamLowPriority, 1024),
-		UpstreamGuest:           div(d.UpstreamGuest, 1024),
-	}
-}
+// codebeat:enable[TOO_MANY_IVARS]
 
-func div(xs []float64, d float64) []float64 {
-	ys := make([]float64, len(xs))
-	for i, x := range xs {
-		ys[i] = x / d
+// Current returns the most recent complete sample of a series, the newest
+// one is still being accumulated by the box.
+func Current(xs []float64) (float64, bool) {
+	switch len(xs) {
+	case 0:
+		return 0, false
+	case 1:
+		return xs[0], true
 	}
-	return ys
+	return xs[1], true
 }
 
-func DecodeTrafficMonitoringData(body string) (*TrafficMonitoringData, error) {
-	t := &[]TrafficMonitoringData{}
+// DecodeTrafficMonitoringData decodes the sync groups of a netMoni response.
+func DecodeTrafficMonitoringData(body string) ([]TrafficMonitoringData, error) {
+	t := &trafficMonitoringResponse{}
 	err := json.Unmarshal([]byte(body), t)
-
 	if err != nil {
 		return nil, err
-	} else {
-		for _, v := range *t {
-			return &v, nil
-		}
 	}
-	return nil, nil
+	return t.Data.SyncGroups, nil
 }
@@ -0,0 +1,50 @@
+package fritz
+
+import (
+	"testing"
+)
+
+func TestDecodeTrafficMonitoringData(t *testing.T) {
+	body := `{"data":{"sync_groups":[
+		{"name":"DSL","mode":"VDSL","ds_bps_max":13125000,"us_bps_max":5000000,
+		 "ds_bps_curr":[412305,10245120,9871360],"ds_mc_bps_curr":[0,0,0],
+		 "us_realtime_bps_curr":[0,512,0],"us_important_bps_curr":[128,2048,1024],
+		 "us_default_bps_curr":[1536,40960,38912],"us_background_bps_curr":[0,0,0]},
+		{"name":"LTE","mode":"LTE","ds_bps_max":6250000,"us_bps_max":1250000,
+		 "ds_bps_curr":[2048],"us_default_bps_curr":[]}
+	]}}`
+	groups, err := DecodeTrafficMonitoringData(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 || groups[0].Name != "DSL" || groups[1].Name != "LTE" {
+		t.Fatalf("sync groups %+v, want DSL and LTE", groups)
+	}
+	if groups[0].Mode != "VDSL" || groups[0].DownstreamMax != 13125000 || groups[1].UpstreamMax != 1250000 {
+		t.Errorf("sync groups %+v, want mode and maximum rates", groups)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		series []float64
+		want   float64
+		ok     bool
+	}{
+		// index 0 is still being accumulated, index 1 is the current one
+		{"downstream", groups[0].DownstreamInternet, 10245120, true},
+		{"realtime", groups[0].UpstreamRealtime, 512, true},
+		{"default priority", groups[0].UpstreamDefaultPriority, 40960, true},
+		{"single sample", groups[1].DownstreamInternet, 2048, true},
+		{"empty", groups[1].UpstreamDefaultPriority, 0, false},
+		{"missing", groups[1].DownstreamGuest, 0, false},
+	} {
+		got, ok := Current(tc.series)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("%s: Current(%v) = %v, %v, want %v, %v", tc.name, tc.series, got, ok, tc.want, tc.ok)
+		}
+	}
+
+	if _, err := DecodeTrafficMonitoringData(`{"data":`); err == nil {
+		t.Errorf("DecodeTrafficMonitoringData() of a truncated answer succeeded")
+	}
+}
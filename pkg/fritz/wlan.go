@@ -0,0 +1,194 @@
+package fritz
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// WLANService returns the TR-064 service type of the n-th WLAN, usually 1
+// is 2.4 GHz, 2 is 5 GHz, 3 the guest network and 4 6 GHz or guest on 5 GHz.
+func WLANService(n int) string {
+	return fmt.Sprintf("urn:dslforum-org:service:WLANConfiguration:%d", n)
+}
+
+// WLANRadio is the state of one WLAN of the box. Values older firmware
+// doesn't report are nil.
+type WLANRadio struct {
+	Index        int
+	Enabled      bool
+	Status       string
+	SSID         string
+	Standard     string
+	Band         string
+	Channel      *float64
+	ChannelWidth *float64
+	TxPower      *float64
+	Clients      float64
+}
+
+var (
+	wlanBandArgs         = []string{"NewX_AVM-DE_FrequencyBand", "NewFrequencyBand"}
+	wlanChannelWidthArgs = []string{"NewX_AVM-DE_ChannelWidth", "NewChannelWidth"}
+	wlanTxPowerArgs      = []string{"NewX_AVM-DE_TransmitPower", "NewTransmitPower"}
+)
+
+// wlanArg returns the first of names set in args, firmware versions name
+// some values differently.
+func wlanArg(args map[string]string, names []string) string {
+	for _, name := range names {
+		if v, ok := args[name]; ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// DecodeWLANRadio builds WLANRadio from the merged output arguments of
+// GetInfo, GetTotalAssociations, GetChannelInfo and X_AVM-DE_GetWLANExtInfo
+// of the n-th WLANConfiguration service.
+func DecodeWLANRadio(n int, args map[string]string) *WLANRadio {
+	r := &WLANRadio{
+		Index:        n,
+		Enabled:      args["NewEnable"] == "1",
+		Status:       args["NewStatus"],
+		SSID:         args["NewSSID"],
+		Standard:     args["NewStandard"],
+		Band:         wlanBand(wlanArg(args, wlanBandArgs)),
+		Channel:      floatArg(args["NewChannel"]),
+		ChannelWidth: floatArg(wlanArg(args, wlanChannelWidthArgs)),
+		TxPower:      floatArg(wlanArg(args, wlanTxPowerArgs)),
+	}
+	clients := floatArg(args["NewTotalAssociations"])
+	if clients != nil {
+		r.Clients = *clients
+	}
+	if r.Band == "" && r.Channel != nil {
+		// guess from the channel for boxes not reporting the band
+		r.Band = "2.4GHz"
+		if *r.Channel > 14 {
+			r.Band = "5GHz"
+		}
+	}
+	return r
+}
+
+// wlanBand turns the frequency band in MHz as reported by the box into a label.
+func wlanBand(band string) string {
+	switch band {
+	case "2400":
+		return "2.4GHz"
+	case "5000":
+		return "5GHz"
+	case "6000":
+		return "6GHz"
+	}
+	return band
+}
+
+// WLANNeighbor is a foreign network seen by the box.
+type WLANNeighbor struct {
+	SSID    string
+	BSSID   string
+	Band    string
+	Channel int
+	RSSI    float64
+}
+
+// WLANChannelUsage is how busy the box measured a channel, in percent.
+type WLANChannelUsage struct {
+	Band        string
+	Channel     int
+	Utilization float64
+}
+
+// wlanChannelPage is the part of the channel page (page=chan) the exporter
+// uses: the networks found by the last scan and the measured load of every
+// channel. Bands are given in MHz like in TR-064, numbers as string or
+// number depending on the firmware.
+type wlanChannelPage struct {
+	Data struct {
+		ScanList []struct {
+			SSID    string       `json:"ssid"`
+			MAC     string       `json:"mac"`
+			Band    string       `json:"band"`
+			Channel LenientFloat `json:"channel"`
+			RSSI    LenientFloat `json:"rssi"`
+		} `json:"scanlist"`
+		Usage []struct {
+			Band        string       `json:"band"`
+			Channel     LenientFloat `json:"channel"`
+			Utilization LenientFloat `json:"utilization"`
+		} `json:"usage"`
+	} `json:"data"`
+}
+
+// DecodeWLANChannels decodes the channel page. A network listed more than
+// once per band and channel is reported once with its strongest signal,
+// repeated channel loads with the highest one, as each becomes a series.
+func DecodeWLANChannels(body string) ([]WLANNeighbor, []WLANChannelUsage, error) {
+	page := wlanChannelPage{}
+	err := json.Unmarshal([]byte(body), &page)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var neighbors []WLANNeighbor
+	seen := make(map[WLANNeighbor]int)
+	for _, n := range page.Data.ScanList {
+		if n.Channel <= 0 {
+			continue
+		}
+		neighbor := WLANNeighbor{
+			SSID:    n.SSID,
+			BSSID:   strings.ToUpper(n.MAC),
+			Band:    channelBand(n.Band, int(n.Channel)),
+			Channel: int(n.Channel),
+			RSSI:    float64(n.RSSI),
+		}
+		key := WLANNeighbor{BSSID: neighbor.BSSID, Band: neighbor.Band, Channel: neighbor.Channel}
+		if i, ok := seen[key]; ok {
+			if neighbor.RSSI > neighbors[i].RSSI {
+				neighbors[i].RSSI = neighbor.RSSI
+			}
+			continue
+		}
+		seen[key] = len(neighbors)
+		neighbors = append(neighbors, neighbor)
+	}
+
+	var usage []WLANChannelUsage
+	measured := make(map[WLANChannelUsage]int)
+	for _, u := range page.Data.Usage {
+		if u.Channel <= 0 {
+			continue
+		}
+		channel := WLANChannelUsage{
+			Band:        channelBand(u.Band, int(u.Channel)),
+			Channel:     int(u.Channel),
+			Utilization: float64(u.Utilization),
+		}
+		key := WLANChannelUsage{Band: channel.Band, Channel: channel.Channel}
+		if i, ok := measured[key]; ok {
+			if channel.Utilization > usage[i].Utilization {
+				usage[i].Utilization = channel.Utilization
+			}
+			continue
+		}
+		measured[key] = len(usage)
+		usage = append(usage, channel)
+	}
+	return neighbors, usage, nil
+}
+
+// channelBand labels the band in MHz, guessed from the channel if the
+// firmware doesn't send it.
+func channelBand(band string, channel int) string {
+	if band != "" {
+		return wlanBand(band)
+	}
+	if channel > 14 {
+		return "5GHz"
+	}
+	return "2.4GHz"
+}
@@ -0,0 +1,38 @@
+package fritz
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeWLANChannels(t *testing.T) {
+	body, err := os.ReadFile("testdata/chan.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	neighbors, usage, err := DecodeWLANChannels(string(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantNeighbors := []WLANNeighbor{
+		{SSID: "FRITZ!Box 7590 XL", BSSID: "3C:A6:2F:11:22:33", Band: "2.4GHz", Channel: 1, RSSI: -71},
+		// listed twice, the stronger signal is kept
+		{SSID: "Vodafone-A1B2", BSSID: "38:10:D5:AA:BB:CC", Band: "2.4GHz", Channel: 6, RSSI: -77},
+		{SSID: "", BSSID: "38:10:D5:AA:BB:CD", Band: "2.4GHz", Channel: 6, RSSI: -79},
+		{SSID: "o2-WLAN42", BSSID: "9C:C7:A6:01:02:03", Band: "5GHz", Channel: 36, RSSI: -85},
+		{SSID: "Nachbar 6G", BSSID: "3C:A6:2F:44:55:66", Band: "6GHz", Channel: 1, RSSI: -88},
+	}
+	if !reflect.DeepEqual(neighbors, wantNeighbors) {
+		t.Errorf("neighbors = %+v, want %+v", neighbors, wantNeighbors)
+	}
+	wantUsage := []WLANChannelUsage{
+		{Band: "2.4GHz", Channel: 1, Utilization: 23},
+		{Band: "2.4GHz", Channel: 6, Utilization: 44},
+		{Band: "5GHz", Channel: 36, Utilization: 8},
+		{Band: "6GHz", Channel: 1, Utilization: 2},
+	}
+	if !reflect.DeepEqual(usage, wantUsage) {
+		t.Errorf("usage = %+v, want %+v", usage, wantUsage)
+	}
+}
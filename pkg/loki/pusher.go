@@ -1,22 +1,29 @@
 package loki
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"time"
 
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
 	"github.com/go-resty/resty/v2"
 )
 
 type Pusher struct {
 	URL    string
+	Labels map[string]string
 	client *resty.Client
+	logger log.Logger
 }
 
-func New(lokiURL string) Pusher {
+func New(lokiURL string, logger log.Logger) Pusher {
 	pusher := Pusher{}
 	pusher.client = resty.New()
+	pusher.logger = logger
 	pusher.URL = lokiURL + "loki/api/v1/push"
+	pusher.Labels = map[string]string{"app": "fritzbox"}
 
 	return pusher
 }
@@ -25,7 +32,11 @@ func (p *Pusher) Push(lines [][]byte) error {
 	if len(lines) == 0 {
 		return nil
 	}
-	tmpbody := "{\"streams\": [{ \"stream\": { \"app\": \"fritzbox\" }, \"values\": ["
+	stream, err := json.Marshal(p.Labels)
+	if err != nil {
+		return err
+	}
+	tmpbody := "{\"streams\": [{ \"stream\": " + string(stream) + ", \"values\": ["
 	now := fmt.Sprint(time.Now().UnixNano())
 	for _, l := range lines {
 		tmpbody += "[\"" + now + "\"," + strconv.Quote(string(l)) + "]," // 20060102150405
@@ -37,6 +48,6 @@ func (p *Pusher) Push(lines [][]byte) error {
 	if err != nil {
 		return err
 	}
-	fmt.Println("message: log push result, response: " + resp.String())
+	level.Debug(p.logger).Log("msg", "log push result", "status", resp.Status(), "response", resp.String())
 	return nil
 }
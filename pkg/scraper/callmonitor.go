@@ -0,0 +1,232 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/wbwue/FritzExporter/pkg/fritz"
+	"github.com/wbwue/FritzExporter/pkg/loki"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+func init() {
+	registerCollector("callmonitor", false, func(s *Scraper) Collector {
+		u, err := url.Parse(s.target.URL)
+		if err != nil {
+			return nil
+		}
+		m := newCallMonitor(net.JoinHostPort(u.Hostname(), strconv.Itoa(fritz.CallMonitorPort)), s.pusher(), s.logger)
+		return newCallMonitorCollector(s, m)
+	})
+}
+
+// callMonitorOf returns the monitor of the callmonitor collector among
+// collectors, nil if it isn't enabled.
+func callMonitorOf(collectors []*cachedCollector) *callMonitor {
+	for _, c := range collectors {
+		if mc, ok := c.collector.(*callMonitorCollector); ok {
+			return mc.monitor
+		}
+	}
+	return nil
+}
+
+// callMonitorEvents are the event types counted by the callmonitor collector.
+var callMonitorEvents = []string{"ring", "call", "connect", "disconnect"}
+
+// callMonitorPushBacklog is the number of events waiting for Loki, further
+// events are not pushed until Loki catches up.
+const callMonitorPushBacklog = 256
+
+// callMonitor keeps a connection to the call monitor of the box while the
+// scrapers run, tracks the active calls and pushes the events to Loki.
+// It stays with its scraper across reloads, so they don't interrupt it.
+type callMonitor struct {
+	logger  log.Logger
+	monitor *fritz.CallMonitor
+	// pending events are pushed to Loki by a goroutine of their own, so a
+	// slow Loki doesn't hold up reading the call monitor
+	pending chan []byte
+
+	mu        sync.Mutex
+	logPusher loki.Pusher
+	// active maps the connection id of a call to its direction
+	active map[string]string
+	events map[string]float64
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newCallMonitor(address string, logPusher loki.Pusher, logger log.Logger) *callMonitor {
+	m := &callMonitor{
+		logger:    log.With(logger, "address", address),
+		logPusher: logPusher,
+		pending:   make(chan []byte, callMonitorPushBacklog),
+		active:    make(map[string]string),
+		events:    make(map[string]float64),
+	}
+	m.monitor = &fritz.CallMonitor{
+		Address: address,
+		Backoff: fritz.DefaultCallMonitorBackoff(),
+		OnEvent: m.handle,
+		OnError: m.handleError,
+	}
+	return m
+}
+
+// start connects to the call monitor unless it is running already.
+func (m *callMonitor) start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.done = make(chan struct{})
+	level.Info(m.logger).Log("msg", "starting call monitor")
+	go func(done chan struct{}) {
+		defer close(done)
+		pushed := make(chan struct{})
+		go func() {
+			defer close(pushed)
+			m.push(ctx)
+		}()
+		m.monitor.Run(ctx)
+		<-pushed
+	}(m.done)
+}
+
+// push sends the pending events to Loki until ctx is done.
+func (m *callMonitor) push(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case line := <-m.pending:
+			m.mu.Lock()
+			logPusher := m.logPusher
+			m.mu.Unlock()
+			err := logPusher.Push([][]byte{line})
+			if err != nil {
+				level.Warn(m.logger).Log("msg", "cannot send call monitor event", "err", err)
+			}
+		}
+	}
+}
+
+// stop disconnects and waits until the monitor is done.
+func (m *callMonitor) stop() {
+	m.mu.Lock()
+	cancel, done := m.cancel, m.done
+	m.cancel = nil
+	m.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// setLogPusher switches to the Loki settings of a new configuration.
+func (m *callMonitor) setLogPusher(logPusher loki.Pusher) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logPusher = logPusher
+}
+
+func (m *callMonitor) handle(e fritz.CallEvent) {
+	level.Debug(m.logger).Log("msg", "call monitor event", "event", e.Event, "connection_id", e.ConnectionID)
+	m.mu.Lock()
+	m.events[e.Event]++
+	switch e.Event {
+	case "ring":
+		m.active[e.ConnectionID] = "incoming"
+	case "call":
+		m.active[e.ConnectionID] = "outgoing"
+	case "disconnect":
+		delete(m.active, e.ConnectionID)
+	}
+	m.mu.Unlock()
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		level.Warn(m.logger).Log("msg", "cannot encode call monitor event", "err", err)
+		return
+	}
+	select {
+	case m.pending <- line:
+	default:
+		level.Warn(m.logger).Log("msg", "dropping call monitor event, Loki is too slow", "event", e.Event, "connection_id", e.ConnectionID)
+	}
+}
+
+func (m *callMonitor) handleError(err error) {
+	if errors.Is(err, fritz.ErrInvalidResponse) {
+		level.Warn(m.logger).Log("msg", "skipping call monitor line", "err", err)
+		return
+	}
+	level.Warn(m.logger).Log("msg", "call monitor connection failed", "err", err)
+	// the calls that end while disconnected are never reported
+	m.mu.Lock()
+	m.active = make(map[string]string)
+	m.mu.Unlock()
+}
+
+// callMonitorCollector exports the state of its call monitor.
+type callMonitorCollector struct {
+	monitor     *callMonitor
+	connected   *prometheus.Desc
+	activeCalls *prometheus.Desc
+	events      *prometheus.Desc
+}
+
+func newCallMonitorCollector(s *Scraper, m *callMonitor) *callMonitorCollector {
+	return &callMonitorCollector{
+		monitor:     m,
+		connected:   s.newDesc("fritzbox_callmonitor_connected", "Gauge showing whether the exporter is connected to the call monitor of the box"),
+		activeCalls: s.newDesc("fritzbox_callmonitor_active_calls", "Gauge showing the calls ringing or in progress", "direction"),
+		events:      s.newDesc("fritzbox_callmonitor_events_total", "Counter of call monitor events received by the exporter", "event"),
+	}
+}
+
+func (c *callMonitorCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.connected
+	ch <- c.activeCalls
+	ch <- c.events
+}
+
+func (c *callMonitorCollector) Update(ch chan<- prometheus.Metric) error {
+	m := c.monitor
+	connected := 0.0
+	if m.monitor.Connected() {
+		connected = 1
+	}
+	active := map[string]float64{"incoming": 0, "outgoing": 0}
+	events := make(map[string]float64, len(callMonitorEvents))
+	m.mu.Lock()
+	for _, direction := range m.active {
+		active[direction]++
+	}
+	for _, event := range callMonitorEvents {
+		events[event] = m.events[event]
+	}
+	m.mu.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(c.connected, prometheus.GaugeValue, connected)
+	for direction, count := range active {
+		ch <- prometheus.MustNewConstMetric(c.activeCalls, prometheus.GaugeValue, count, direction)
+	}
+	for event, count := range events {
+		ch <- prometheus.MustNewConstMetric(c.events, prometheus.CounterValue, count, event)
+	}
+	return nil
+}
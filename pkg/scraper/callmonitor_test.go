@@ -0,0 +1,232 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/wbwue/FritzExporter/pkg/config"
+	"github.com/wbwue/FritzExporter/pkg/fritz"
+	"github.com/wbwue/FritzExporter/pkg/loki"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// lokiStub records the lines pushed to it.
+func lokiStub(t *testing.T) (*httptest.Server, chan fritz.CallEvent) {
+	pushed := make(chan fritz.CallEvent, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Streams []struct {
+				Values [][2]string `json:"values"`
+			} `json:"streams"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding Loki push: %v", err)
+			return
+		}
+		for _, s := range body.Streams {
+			for _, v := range s.Values {
+				var e fritz.CallEvent
+				if err := json.Unmarshal([]byte(v[1]), &e); err != nil {
+					t.Errorf("decoding pushed event %q: %v", v[1], err)
+					continue
+				}
+				pushed <- e
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, pushed
+}
+
+// callMonitorMetrics returns the metrics of c by name and label value.
+func callMonitorMetrics(t *testing.T, c *callMonitorCollector) map[string]float64 {
+	ch := make(chan prometheus.Metric, 16)
+	if err := c.Update(ch); err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+	names := map[*prometheus.Desc]string{c.connected: "connected", c.activeCalls: "active", c.events: "events"}
+	metrics := make(map[string]float64)
+	for m := range ch {
+		var d dto.Metric
+		if err := m.Write(&d); err != nil {
+			t.Fatal(err)
+		}
+		key := names[m.Desc()]
+		for _, l := range d.GetLabel() {
+			if l.GetName() != "box" {
+				key += "/" + l.GetValue()
+			}
+		}
+		switch {
+		case d.GetGauge() != nil:
+			metrics[key] = d.GetGauge().GetValue()
+		case d.GetCounter() != nil:
+			metrics[key] = d.GetCounter().GetValue()
+		}
+	}
+	return metrics
+}
+
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCallMonitor(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	conns := make(chan net.Conn)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				close(conns)
+				return
+			}
+			conns <- conn
+		}
+	}()
+	accept := func() net.Conn {
+		t.Helper()
+		select {
+		case conn := <-conns:
+			return conn
+		case <-time.After(5 * time.Second):
+			t.Fatal("call monitor did not connect")
+			return nil
+		}
+	}
+	send := func(conn net.Conn, lines ...string) {
+		t.Helper()
+		for _, line := range lines {
+			if _, err := fmt.Fprintf(conn, "%s\r\n", line); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	srv, pushed := lokiStub(t)
+	m := newCallMonitor(l.Addr().String(), loki.New(srv.URL+"/", log.NewNopLogger()), log.NewNopLogger())
+	m.monitor.Backoff = fritz.Backoff{Min: 10 * time.Millisecond, Max: 10 * time.Millisecond, Factor: 1}
+	c := newCallMonitorCollector(&Scraper{target: config.Target{Name: "main"}}, m)
+	m.start()
+	defer m.stop()
+
+	conn := accept()
+	send(conn,
+		"18.10.26 12:00:00;RING;0;01701234567;4930123456;SIP0;",
+		"18.10.26 12:00:05;CALL;1;10;4930123456;08001234;SIP1;",
+	)
+	eventually(t, "ring and call", func() bool { return callMonitorMetrics(t, c)["events/call"] == 1 })
+	metrics := callMonitorMetrics(t, c)
+	for key, want := range map[string]float64{"connected": 1, "active/incoming": 1, "active/outgoing": 1, "events/ring": 1} {
+		if metrics[key] != want {
+			t.Errorf("%s = %v, want %v", key, metrics[key], want)
+		}
+	}
+
+	send(conn,
+		"18.10.26 12:00:07;CONNECT;0;11;01701234567;",
+		"18.10.26 12:01:49;DISCONNECT;0;102;",
+	)
+	eventually(t, "disconnect", func() bool { return callMonitorMetrics(t, c)["events/disconnect"] == 1 })
+	metrics = callMonitorMetrics(t, c)
+	for key, want := range map[string]float64{"active/incoming": 0, "active/outgoing": 1, "events/connect": 1} {
+		if metrics[key] != want {
+			t.Errorf("%s = %v, want %v", key, metrics[key], want)
+		}
+	}
+
+	durations := make(map[string]float64)
+	for i := 0; i < 4; i++ {
+		select {
+		case e := <-pushed:
+			durations[e.Event] = e.DurationSeconds
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%d events pushed to Loki, want 4", i)
+		}
+	}
+	if durations["disconnect"] != 102 || durations["ring"] != 0 {
+		t.Errorf("pushed durations %v, want 102 seconds for the disconnect only", durations)
+	}
+
+	// the outgoing call ends while the connection is down
+	conn.Close()
+	conn = accept()
+	defer conn.Close()
+	eventually(t, "reconnect", func() bool { return m.monitor.Connected() })
+	metrics = callMonitorMetrics(t, c)
+	if metrics["active/outgoing"] != 0 || metrics["events/call"] != 1 {
+		t.Errorf("after reconnect %v, want no active calls and the events kept", metrics)
+	}
+	send(conn, "18.10.26 12:05:00;RING;2;01701234567;4930123456;SIP0;")
+	eventually(t, "ring after reconnect", func() bool { return callMonitorMetrics(t, c)["active/incoming"] == 1 })
+}
+
+// callMonitorOnly enables the callmonitor collector alone, updating it on
+// every collection if enabled.
+func callMonitorOnly(enabled bool) *config.Config {
+	cfg := config.NewConfig()
+	for _, name := range CollectorNames() {
+		cfg.Collectors[name] = config.CollectorConfig{}
+	}
+	cfg.Collectors["callmonitor"] = config.CollectorConfig{Enabled: enabled, Interval: time.Nanosecond}
+	return cfg
+}
+
+func TestReconfigureDuringCollect(t *testing.T) {
+	target := config.Target{Name: "main", URL: "http://127.0.0.1:1"}
+	s := newScraper(callMonitorOnly(true), target, fritz.NewSession(target.URL, "", ""), 0, log.NewNopLogger())
+	s.startCallMonitor()
+	defer s.stop()
+
+	done := make(chan struct{})
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		ch := make(chan prometheus.Metric)
+		go func() {
+			for range ch {
+			}
+		}()
+		defer close(ch)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				s.Collect(ch)
+			}
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		s.reconfigure(callMonitorOnly(i%2 == 1))
+		s.startCallMonitor()
+	}
+	s.reconfigure(callMonitorOnly(false))
+	close(done)
+	<-collected
+
+	if m := s.callMonitor(); m != nil {
+		t.Errorf("call monitor still configured after disabling the collector")
+	}
+}
@@ -0,0 +1,183 @@
+package scraper
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wbwue/FritzExporter/pkg/fritz"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type collectorFactory struct {
+	enabledByDefault bool
+	create           func(s *Scraper) Collector
+}
+
+var factories = make(map[string]collectorFactory)
+
+// registerCollector makes a collector selectable by name. create may return
+// nil if the collector can't work for the given box.
+func registerCollector(name string, enabledByDefault bool, create func(s *Scraper) Collector) {
+	factories[name] = collectorFactory{
+		enabledByDefault: enabledByDefault,
+		create:           create,
+	}
+}
+
+// CollectorNames returns the names of all available collectors, sorted.
+func CollectorNames() []string {
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CollectorEnabledByDefault reports whether the named collector runs unless disabled.
+func CollectorEnabledByDefault(name string) bool {
+	return factories[name].enabledByDefault
+}
+
+// Collector fetches one kind of data from the box. Update is only called
+// when the cached result of the previous call has expired, it sends the
+// metrics for the current state of the box.
+type Collector interface {
+	Describe(ch chan<- *prometheus.Desc)
+	Update(ch chan<- prometheus.Metric) error
+}
+
+// cachedCollector keeps the metrics of the last Update for ttl. Every metric
+// carries the time it was fetched from the box, and as the whole set is
+// replaced on every Update, series of vanished devices disappear with it.
+type cachedCollector struct {
+	name      string
+	collector Collector
+	ttl       time.Duration
+
+	mu          sync.Mutex
+	metrics     []prometheus.Metric
+	updated     time.Time
+	err         error
+	duration    time.Duration
+	lastSuccess time.Time
+	errors      map[string]float64
+}
+
+// collectorStats describe the health of a collector for the exporter's own metrics.
+type collectorStats struct {
+	duration    time.Duration
+	lastSuccess time.Time
+	errors      map[string]float64
+}
+
+func newCachedCollector(name string, collector Collector, ttl time.Duration) *cachedCollector {
+	return &cachedCollector{
+		name:      name,
+		collector: collector,
+		ttl:       ttl,
+		errors:    make(map[string]float64),
+	}
+}
+
+func (c *cachedCollector) setTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+}
+
+func (c *cachedCollector) stats() collectorStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := collectorStats{
+		duration:    c.duration,
+		lastSuccess: c.lastSuccess,
+		errors:      make(map[string]float64, len(c.errors)),
+	}
+	for reason, count := range c.errors {
+		stats.errors[reason] = count
+	}
+	return stats
+}
+
+// collect sends the cached metrics, updating them first if they are older
+// than ttl or force is set. The returned error is the one of the Update the
+// metrics stem from.
+func (c *cachedCollector) collect(ch chan<- prometheus.Metric, force bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if force || c.updated.IsZero() || time.Since(c.updated) >= c.ttl {
+		c.update()
+	}
+	for _, m := range c.metrics {
+		ch <- prometheus.NewMetricWithTimestamp(c.updated, m)
+	}
+	return c.err
+}
+
+// refresh updates the cached metrics if they expired, without sending them.
+func (c *cachedCollector) refresh() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.updated.IsZero() || time.Since(c.updated) >= c.ttl {
+		c.update()
+	}
+	return c.err
+}
+
+func (c *cachedCollector) update() {
+	metrics := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	var collected []prometheus.Metric
+	go func() {
+		for m := range metrics {
+			collected = append(collected, m)
+		}
+		close(done)
+	}()
+	start := time.Now()
+	c.err = c.collector.Update(metrics)
+	close(metrics)
+	<-done
+	c.metrics = collected
+	c.updated = time.Now()
+	c.duration = c.updated.Sub(start)
+	switch {
+	case c.err == nil:
+		c.lastSuccess = c.updated
+	case !errors.Is(c.err, fritz.ErrNotSupported):
+		c.errors[errorReason(c.err)]++
+	}
+}
+
+// errorReason sorts collector errors into a few classes for the reason label.
+func errorReason(err error) string {
+	var blocked *fritz.LoginBlockedError
+	var netErr net.Error
+	var jsonSyntax *json.SyntaxError
+	var jsonType *json.UnmarshalTypeError
+	var xmlSyntax *xml.SyntaxError
+	var soapErr *fritz.SOAPError
+	switch {
+	case errors.As(err, &blocked):
+		return "login_blocked"
+	case errors.Is(err, fritz.ErrLoginFailed):
+		return "login"
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return "timeout"
+		}
+		return "request"
+	case errors.As(err, &soapErr):
+		return "tr064"
+	case errors.As(err, &jsonSyntax), errors.As(err, &jsonType), errors.As(err, &xmlSyntax), errors.Is(err, fritz.ErrInvalidResponse):
+		return "decode"
+	}
+	return "other"
+}
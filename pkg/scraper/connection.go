@@ -0,0 +1,65 @@
+package scraper
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("connection", true, func(s *Scraper) Collector { return newConnectionCollector(s) })
+}
+
+// connectionCollector exports the state of the internet connection and
+// counts reconnects, which show up as an uptime reset or a new address.
+type connectionCollector struct {
+	s          *Scraper
+	up         *prometheus.Desc
+	uptime     *prometheus.Desc
+	info       *prometheus.Desc
+	reconnects *prometheus.Desc
+
+	seen           bool
+	lastUptime     float64
+	lastIPV4       string
+	reconnectCount float64
+}
+
+func newConnectionCollector(s *Scraper) *connectionCollector {
+	return &connectionCollector{
+		s:          s,
+		up:         s.newDesc("fritzbox_wan_connection_up", "Gauge showing whether the internet connection is established"),
+		uptime:     s.newDesc("fritzbox_wan_uptime_seconds", "Gauge showing how long the internet connection is established"),
+		info:       s.newDesc("fritzbox_wan_connection_info", "Gauge showing the external addresses and last error of the internet connection", "status", "external_ipv4", "external_ipv6", "last_error"),
+		reconnects: s.newDesc("fritzbox_wan_reconnects_total", "Counter of reconnects of the internet connection seen by the exporter"),
+	}
+}
+
+func (c *connectionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.up
+	ch <- c.uptime
+	ch <- c.info
+	ch <- c.reconnects
+}
+
+func (c *connectionCollector) Update(ch chan<- prometheus.Metric) error {
+	extIPV6, extIPV4, status, lastError, uptime, err := c.s.getConnectionInfo()
+	if err != nil {
+		return err
+	}
+	up := 0.0
+	if status == "Connected" {
+		up = 1
+	}
+	if c.seen && (uptime < c.lastUptime || (extIPV4 != c.lastIPV4 && extIPV4 != "" && c.lastIPV4 != "")) {
+		c.reconnectCount++
+	}
+	c.seen = true
+	c.lastUptime = uptime
+	if extIPV4 != "" {
+		c.lastIPV4 = extIPV4
+	}
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, up)
+	ch <- prometheus.MustNewConstMetric(c.uptime, prometheus.GaugeValue, uptime)
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, status, extIPV4, extIPV6, lastError)
+	ch <- prometheus.MustNewConstMetric(c.reconnects, prometheus.CounterValue, c.reconnectCount)
+	return nil
+}
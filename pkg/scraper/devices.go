@@ -0,0 +1,120 @@
+package scraper
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("devices", true, func(s *Scraper) Collector { return newDevicesCollector(s) })
+	registerCollector("wlan", true, func(s *Scraper) Collector { return newWlanCollector(s) })
+}
+
+// devicesCollector exports the state of all devices in the LAN device list.
+type devicesCollector struct {
+	s      *Scraper
+	online *prometheus.Desc
+	active *prometheus.Desc
+	speed  *prometheus.Desc
+}
+
+func newDevicesCollector(s *Scraper) *devicesCollector {
+	labels := []string{"name", "ip", "mac", "dev_type"}
+	return &devicesCollector{
+		s:      s,
+		online: s.newDesc("fritzbox_lan_devices_online", "Gauge showing online state of device", labels...),
+		active: s.newDesc("fritzbox_lan_devices_active", "Gauge showing active state of device", labels...),
+		speed:  s.newDesc("fritzbox_lan_devices_speed", "Gauge showing speed of device", labels...),
+	}
+}
+
+func (c *devicesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.online
+	ch <- c.active
+	ch <- c.speed
+}
+
+func (c *devicesCollector) Update(ch chan<- prometheus.Metric) error {
+	l, err := c.s.lanDevices()
+	if err != nil {
+		return err
+	}
+	for _, v := range l.Network {
+		devType := c.s.devType(v.UID)
+		name := c.s.config().DeviceName(v.Name, v.Mac)
+		active, _ := strconv.ParseFloat(v.Active, 64)
+		online, _ := strconv.ParseFloat(v.Online, 64)
+		speed, _ := strconv.ParseFloat(v.Speed, 64)
+		ch <- prometheus.MustNewConstMetric(c.active, prometheus.GaugeValue, active, name, v.IP, v.Mac, devType)
+		ch <- prometheus.MustNewConstMetric(c.online, prometheus.GaugeValue, online, name, v.IP, v.Mac, devType)
+		ch <- prometheus.MustNewConstMetric(c.speed, prometheus.GaugeValue, speed, name, v.IP, v.Mac, devType)
+	}
+	return nil
+}
+
+// wlanCollector fetches the details of every online device, which is one
+// request per device, and exports the WLAN connection of wifi clients.
+type wlanCollector struct {
+	s        *Scraper
+	signal   *prometheus.Desc
+	speed    *prometheus.Desc
+	speedMax *prometheus.Desc
+	info     *prometheus.Desc
+}
+
+func newWlanCollector(s *Scraper) *wlanCollector {
+	labels := []string{"name", "ip", "mac", "dev_type"}
+	return &wlanCollector{
+		s:        s,
+		signal:   s.newDesc("fritzbox_wlan_devices_signal", "Gauge showing signal strength of wifi devices", labels...),
+		speed:    s.newDesc("fritzbox_wlan_devices_speed", "Gauge showing current speed of wifi devices", append(labels, "direction")...),
+		speedMax: s.newDesc("fritzbox_wlan_devices_speed_max", "Gauge showing maximum speed of wifi devices", append(labels, "direction")...),
+		info:     s.newDesc("fritzbox_wlan_devices_info", "Gauge showing maximum speed of wifi devices", append(labels, "band", "standard", "encryption")...),
+	}
+}
+
+func (c *wlanCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.signal
+	ch <- c.speed
+	ch <- c.speedMax
+	ch <- c.info
+}
+
+func (c *wlanCollector) Update(ch chan<- prometheus.Metric) error {
+	l, err := c.s.lanDevices()
+	if err != nil {
+		return err
+	}
+	var queried, failed int
+	var lastErr error
+	for _, v := range l.Network {
+		if v.Online != "1" {
+			continue
+		}
+		queried++
+		fd, err := c.s.deviceSpecificData(v.UID)
+		if err != nil {
+			failed++
+			lastErr = err
+			continue
+		}
+		c.s.setDevType(v.UID, fd.DevType)
+		if fd.DevType != "wlan" {
+			continue
+		}
+		name := c.s.config().DeviceName(v.Name, v.Mac)
+		ch <- prometheus.MustNewConstMetric(c.signal, prometheus.GaugeValue, fd.Wlan.Rssi, name, v.IP, v.Mac, fd.DevType)
+		ch <- prometheus.MustNewConstMetric(c.speed, prometheus.GaugeValue, fd.Wlan.Speed, name, v.IP, v.Mac, fd.DevType, "tx")
+		ch <- prometheus.MustNewConstMetric(c.speedMax, prometheus.GaugeValue, fd.Wlan.SpeedTxMax, name, v.IP, v.Mac, fd.DevType, "tx")
+		ch <- prometheus.MustNewConstMetric(c.speed, prometheus.GaugeValue, fd.Wlan.SpeedRx, name, v.IP, v.Mac, fd.DevType, "rx")
+		ch <- prometheus.MustNewConstMetric(c.speedMax, prometheus.GaugeValue, fd.Wlan.SpeedRxMax, name, v.IP, v.Mac, fd.DevType, "rx")
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, name, v.IP, v.Mac, fd.DevType, fd.Wlan.Band, fd.Wlan.WlanStandard, fd.Wlan.Encryption)
+	}
+	// single devices with odd data shouldn't fail the whole collector
+	if queried > 0 && failed == queried {
+		return fmt.Errorf("all %d device lookups failed: %w", failed, lastErr)
+	}
+	return nil
+}
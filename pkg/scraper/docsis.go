@@ -0,0 +1,79 @@
+package scraper
+
+import (
+	"strconv"
+
+	"github.com/wbwue/FritzExporter/pkg/fritz"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("docsis", false, func(s *Scraper) Collector { return newDocsisCollector(s) })
+}
+
+// docsisCollector exports the channels of cable boxes like the 6591 and 6660.
+type docsisCollector struct {
+	s             *Scraper
+	power         *prometheus.Desc
+	mse           *prometheus.Desc
+	mer           *prometheus.Desc
+	latency       *prometheus.Desc
+	corrected     *prometheus.Desc
+	uncorrectable *prometheus.Desc
+	info          *prometheus.Desc
+}
+
+func newDocsisCollector(s *Scraper) *docsisCollector {
+	labels := []string{"direction", "docsis", "channel_id"}
+	return &docsisCollector{
+		s:             s,
+		power:         s.newDesc("fritzbox_docsis_channel_power_dbmv", "Gauge showing the power level of the cable channel", labels...),
+		mse:           s.newDesc("fritzbox_docsis_channel_mse_db", "Gauge showing the mean square error of the DOCSIS 3.0 downstream channel", labels...),
+		mer:           s.newDesc("fritzbox_docsis_channel_mer_db", "Gauge showing the modulation error ratio of the DOCSIS 3.1 downstream channel", labels...),
+		latency:       s.newDesc("fritzbox_docsis_channel_latency_milliseconds", "Gauge showing the interleaver latency of the downstream channel", labels...),
+		corrected:     s.newDesc("fritzbox_docsis_channel_corrected_errors_total", "Counter of corrected errors on the downstream channel", labels...),
+		uncorrectable: s.newDesc("fritzbox_docsis_channel_uncorrectable_errors_total", "Counter of uncorrectable errors on the downstream channel", labels...),
+		info:          s.newDesc("fritzbox_docsis_channel_info", "Gauge showing modulation and frequency of the cable channel", append(labels, "modulation", "frequency", "multiplex")...),
+	}
+}
+
+func (c *docsisCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.power
+	ch <- c.mse
+	ch <- c.mer
+	ch <- c.latency
+	ch <- c.corrected
+	ch <- c.uncorrectable
+	ch <- c.info
+}
+
+func (c *docsisCollector) Update(ch chan<- prometheus.Metric) error {
+	d, err := c.s.docsisInfo()
+	if err != nil {
+		return err
+	}
+	c.channels(ch, "down", "3.0", d.Downstream.Docsis30)
+	c.channels(ch, "down", "3.1", d.Downstream.Docsis31)
+	c.channels(ch, "up", "3.0", d.Upstream.Docsis30)
+	c.channels(ch, "up", "3.1", d.Upstream.Docsis31)
+	return nil
+}
+
+func (c *docsisCollector) channels(ch chan<- prometheus.Metric, direction string, docsis string, channels []fritz.DocsisChannel) {
+	for _, v := range channels {
+		labels := []string{direction, docsis, strconv.FormatFloat(float64(v.ChannelID), 'f', -1, 64)}
+		send := func(desc *prometheus.Desc, valueType prometheus.ValueType, value *fritz.LenientFloat) {
+			if value != nil {
+				ch <- prometheus.MustNewConstMetric(desc, valueType, float64(*value), labels...)
+			}
+		}
+		send(c.power, prometheus.GaugeValue, v.PowerLevel)
+		send(c.mse, prometheus.GaugeValue, v.MSE)
+		send(c.mer, prometheus.GaugeValue, v.MER)
+		send(c.latency, prometheus.GaugeValue, v.Latency)
+		send(c.corrected, prometheus.CounterValue, v.CorrErrors)
+		send(c.uncorrectable, prometheus.CounterValue, v.NonCorrErrors)
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, append(labels, v.Type, v.Frequency, v.Multiplex)...)
+	}
+}
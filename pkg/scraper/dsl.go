@@ -0,0 +1,92 @@
+package scraper
+
+import (
+	"github.com/wbwue/FritzExporter/pkg/fritz"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("dsl", false, func(s *Scraper) Collector { return newDSLCollector(s) })
+}
+
+// dslCollector exports the DSL line statistics, boxes without DSL don't
+// offer the service and are skipped.
+type dslCollector struct {
+	s                  *Scraper
+	up                 *prometheus.Desc
+	info               *prometheus.Desc
+	syncRate           *prometheus.Desc
+	attainableRate     *prometheus.Desc
+	snrMargin          *prometheus.Desc
+	attenuation        *prometheus.Desc
+	crcErrors          *prometheus.Desc
+	fecErrors          *prometheus.Desc
+	erroredSeconds     *prometheus.Desc
+	severelyErroredSec *prometheus.Desc
+	resyncs            *prometheus.Desc
+	lossOfFraming      *prometheus.Desc
+	initErrors         *prometheus.Desc
+}
+
+func newDSLCollector(s *Scraper) *dslCollector {
+	return &dslCollector{
+		s:                  s,
+		up:                 s.newDesc("fritzbox_dsl_up", "Gauge showing whether the DSL line is in sync"),
+		info:               s.newDesc("fritzbox_dsl_info", "Gauge showing the state of the DSL line as reported by the box", "status"),
+		syncRate:           s.newDesc("fritzbox_dsl_sync_rate_bits_per_second", "Gauge showing the current sync rate of the DSL line", "direction"),
+		attainableRate:     s.newDesc("fritzbox_dsl_attainable_rate_bits_per_second", "Gauge showing the maximum attainable rate of the DSL line", "direction"),
+		snrMargin:          s.newDesc("fritzbox_dsl_snr_margin_db", "Gauge showing the signal to noise ratio margin of the DSL line", "direction"),
+		attenuation:        s.newDesc("fritzbox_dsl_attenuation_db", "Gauge showing the attenuation of the DSL line", "direction"),
+		crcErrors:          s.newDesc("fritzbox_dsl_crc_errors_total", "Counter of CRC errors on the DSL line", "direction"),
+		fecErrors:          s.newDesc("fritzbox_dsl_fec_errors_total", "Counter of errors corrected by FEC on the DSL line", "direction"),
+		erroredSeconds:     s.newDesc("fritzbox_dsl_errored_seconds_total", "Counter of seconds with errors on the DSL line"),
+		severelyErroredSec: s.newDesc("fritzbox_dsl_severely_errored_seconds_total", "Counter of seconds with severe errors on the DSL line"),
+		resyncs:            s.newDesc("fritzbox_dsl_resyncs_total", "Counter of resynchronisations of the DSL line"),
+		lossOfFraming:      s.newDesc("fritzbox_dsl_loss_of_framing_total", "Counter of loss of framing failures on the DSL line"),
+		initErrors:         s.newDesc("fritzbox_dsl_init_errors_total", "Counter of failed initialisations of the DSL line"),
+	}
+}
+
+func (c *dslCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.up
+	ch <- c.info
+	ch <- c.syncRate
+	ch <- c.attainableRate
+	ch <- c.snrMargin
+	ch <- c.attenuation
+	ch <- c.crcErrors
+	ch <- c.fecErrors
+	ch <- c.erroredSeconds
+	ch <- c.severelyErroredSec
+	ch <- c.resyncs
+	ch <- c.lossOfFraming
+	ch <- c.initErrors
+}
+
+func (c *dslCollector) Update(ch chan<- prometheus.Metric) error {
+	d, err := c.s.dslInfo()
+	if err != nil {
+		return err
+	}
+	up := 0.0
+	if d.Status == "Up" {
+		up = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, up)
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, d.Status)
+	for direction, v := range map[string]fritz.DSLDirection{"up": d.Upstream, "down": d.Downstream} {
+		ch <- prometheus.MustNewConstMetric(c.syncRate, prometheus.GaugeValue, v.SyncRate, direction)
+		ch <- prometheus.MustNewConstMetric(c.attainableRate, prometheus.GaugeValue, v.AttainableRate, direction)
+		ch <- prometheus.MustNewConstMetric(c.snrMargin, prometheus.GaugeValue, v.SNRMargin, direction)
+		ch <- prometheus.MustNewConstMetric(c.attenuation, prometheus.GaugeValue, v.Attenuation, direction)
+		ch <- prometheus.MustNewConstMetric(c.crcErrors, prometheus.CounterValue, v.CRCErrors, direction)
+		ch <- prometheus.MustNewConstMetric(c.fecErrors, prometheus.CounterValue, v.FECErrors, direction)
+	}
+	ch <- prometheus.MustNewConstMetric(c.erroredSeconds, prometheus.CounterValue, d.ErroredSeconds)
+	ch <- prometheus.MustNewConstMetric(c.severelyErroredSec, prometheus.CounterValue, d.SeverelyErroredSecs)
+	ch <- prometheus.MustNewConstMetric(c.resyncs, prometheus.CounterValue, d.Resyncs)
+	ch <- prometheus.MustNewConstMetric(c.lossOfFraming, prometheus.CounterValue, d.LossOfFraming)
+	ch <- prometheus.MustNewConstMetric(c.initErrors, prometheus.CounterValue, d.InitErrors+d.InitTimeouts)
+	return nil
+}
@@ -0,0 +1,61 @@
+package scraper
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("firmware", true, func(s *Scraper) Collector { return newFirmwareCollector(s) })
+}
+
+// firmwareCollector exports identity, uptime and firmware update state of
+// the box and counts reboots, which show up as an uptime reset.
+type firmwareCollector struct {
+	s               *Scraper
+	info            *prometheus.Desc
+	uptime          *prometheus.Desc
+	reboots         *prometheus.Desc
+	updateAvailable *prometheus.Desc
+
+	seen        bool
+	lastUptime  float64
+	rebootCount float64
+}
+
+func newFirmwareCollector(s *Scraper) *firmwareCollector {
+	return &firmwareCollector{
+		s:               s,
+		info:            s.newDesc("fritzbox_firmware_info", "Gauge showing model, serial number and firmware version of the box as reported via TR-064", "model", "serial", "version"),
+		uptime:          s.newDesc("fritzbox_uptime_seconds", "Gauge showing how long the box is running since the last reboot"),
+		reboots:         s.newDesc("fritzbox_reboots_total", "Counter of reboots of the box seen by the exporter"),
+		updateAvailable: s.newDesc("fritzbox_firmware_update_available", "Gauge showing whether a firmware update is available for the box", "current", "available"),
+	}
+}
+
+func (c *firmwareCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.info
+	ch <- c.uptime
+	ch <- c.reboots
+	ch <- c.updateAvailable
+}
+
+func (c *firmwareCollector) Update(ch chan<- prometheus.Metric) error {
+	info, err := c.s.deviceInfo()
+	if err != nil {
+		return err
+	}
+	if c.seen && info.Uptime < c.lastUptime {
+		c.rebootCount++
+	}
+	c.seen = true
+	c.lastUptime = info.Uptime
+	available := 0.0
+	if info.UpdateAvailable {
+		available = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, info.Model, info.Serial, info.SoftwareVersion)
+	ch <- prometheus.MustNewConstMetric(c.uptime, prometheus.GaugeValue, info.Uptime)
+	ch <- prometheus.MustNewConstMetric(c.reboots, prometheus.CounterValue, c.rebootCount)
+	ch <- prometheus.MustNewConstMetric(c.updateAvailable, prometheus.GaugeValue, available, info.SoftwareVersion, info.NewVersion)
+	return nil
+}
@@ -0,0 +1,105 @@
+package scraper
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+func init() {
+	registerCollector("link", true, func(s *Scraper) Collector { return newLinkCollector(s) })
+}
+
+// linkCollector exports the WAN link properties and traffic counters
+// reported via TR-064.
+type linkCollector struct {
+	s          *Scraper
+	linkUp     *prometheus.Desc
+	upstream   *prometheus.Desc
+	downstream *prometheus.Desc
+	accessType *prometheus.Desc
+	sent       *prometheus.Desc
+	received   *prometheus.Desc
+
+	sentCounter     monotonicCounter
+	receivedCounter monotonicCounter
+}
+
+func newLinkCollector(s *Scraper) *linkCollector {
+	return &linkCollector{
+		s:          s,
+		linkUp:     s.newDesc("fritzbox_wan_physical_link_up", "Gauge showing whether the physical WAN link is up"),
+		upstream:   s.newDesc("fritzbox_wan_layer1_upstream_max_bits_per_second", "Gauge showing the maximum upstream rate of the WAN link"),
+		downstream: s.newDesc("fritzbox_wan_layer1_downstream_max_bits_per_second", "Gauge showing the maximum downstream rate of the WAN link"),
+		accessType: s.newDesc("fritzbox_wan_access_type_info", "Gauge showing the access type of the WAN link", "access_type"),
+		sent:       s.newDesc("fritzbox_wan_sent_bytes_total", "Counter of bytes sent over the WAN link"),
+		received:   s.newDesc("fritzbox_wan_received_bytes_total", "Counter of bytes received over the WAN link"),
+	}
+}
+
+func (c *linkCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.linkUp
+	ch <- c.upstream
+	ch <- c.downstream
+	ch <- c.accessType
+	ch <- c.sent
+	ch <- c.received
+}
+
+func (c *linkCollector) Update(ch chan<- prometheus.Metric) error {
+	info, err := c.s.getLinkInfo()
+	if err != nil {
+		return err
+	}
+	linkUp := 0.0
+	if info.linkStatus == "Up" {
+		linkUp = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.linkUp, prometheus.GaugeValue, linkUp)
+	ch <- prometheus.MustNewConstMetric(c.upstream, prometheus.GaugeValue, info.upstream)
+	ch <- prometheus.MustNewConstMetric(c.downstream, prometheus.GaugeValue, info.downstream)
+	ch <- prometheus.MustNewConstMetric(c.accessType, prometheus.GaugeValue, 1, info.wanAccessType)
+	uptime, err := c.s.uptime()
+	if err != nil {
+		// reboots are told from wraps by the size of the drop then
+		level.Debug(c.s.logger).Log("msg", "Failed to get uptime", "err", err)
+		uptime = -1
+	}
+	ch <- prometheus.MustNewConstMetric(c.sent, prometheus.CounterValue, c.sentCounter.update(info.bytesSent, info.counterWrap, uptime))
+	ch <- prometheus.MustNewConstMetric(c.received, prometheus.CounterValue, c.receivedCounter.update(info.bytesReceived, info.counterWrap, uptime))
+	return nil
+}
+
+// monotonicCounter turns a counter of the box, which wraps around and
+// starts over when the box reboots, into one that only ever increases.
+type monotonicCounter struct {
+	started    bool
+	last       float64
+	lastUptime float64
+	total      float64
+}
+
+// update adds the difference to the previous value to the total. If the
+// uptime of the box went down, it rebooted and value was counted from 0.
+// Otherwise a value below the previous one is a wrap around if the counter
+// wraps at wrap. With an unknown uptime, below 0, a drop bigger than half
+// of wrap is taken as wrap around and a smaller one as reboot.
+func (m *monotonicCounter) update(value float64, wrap float64, uptime float64) float64 {
+	rebooted := m.started && uptime >= 0 && m.lastUptime >= 0 && uptime < m.lastUptime
+	switch {
+	case !m.started:
+		m.started = true
+		m.total = value
+	case rebooted:
+		m.total += value
+	case value >= m.last:
+		m.total += value - m.last
+	case wrap > 0 && (uptime >= 0 && m.lastUptime >= 0 || m.last-value > wrap/2):
+		m.total += wrap - m.last + value
+	default:
+		m.total += value
+	}
+	m.last = value
+	m.lastUptime = uptime
+	return m.total
+}
@@ -0,0 +1,34 @@
+package scraper
+
+import (
+	"testing"
+)
+
+func TestMonotonicCounter(t *testing.T) {
+	const wrap = 1 << 32
+	for _, tc := range []struct {
+		name   string
+		values []float64
+		uptime []float64
+		want   float64
+	}{
+		{"increase", []float64{100, 250, 1000}, []float64{10, 20, 30}, 1000},
+		{"wrap", []float64{wrap - 100, 50}, []float64{10, 20}, wrap + 50},
+		{"small wrap", []float64{1000, 10}, []float64{10, 20}, wrap + 10},
+		{"reboot above half of wrap", []float64{3 << 30, 500}, []float64{86400, 60}, 3<<30 + 500},
+		{"reboot without drop", []float64{1000, 2000}, []float64{86400, 60}, 3000},
+		{"wrap without uptime", []float64{wrap - 100, 50}, []float64{-1, -1}, wrap + 50},
+		{"reboot without uptime", []float64{1000, 10}, []float64{-1, -1}, 1010},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var c monotonicCounter
+			var got float64
+			for i, value := range tc.values {
+				got = c.update(value, wrap, tc.uptime[i])
+			}
+			if got != tc.want {
+				t.Errorf("total = %.0f, want %.0f", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package scraper
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("logs", true, func(s *Scraper) Collector {
+		// without a log file there is nowhere to write the lines to
+		if s.target.LogPath == "" {
+			return nil
+		}
+		return newLogsCollector(s)
+	})
+}
+
+// logsCollector ships new lines of the box event log to the log file and
+// Loki whenever it is updated.
+type logsCollector struct {
+	s      *Scraper
+	newest *prometheus.Desc
+}
+
+func newLogsCollector(s *Scraper) *logsCollector {
+	return &logsCollector{
+		s:      s,
+		newest: s.newDesc("fritzbox_log_newest_entry_timestamp_seconds", "Gauge showing the time of the newest entry in the box event log"),
+	}
+}
+
+func (c *logsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.newest
+}
+
+func (c *logsCollector) Update(ch chan<- prometheus.Metric) error {
+	err := c.s.queryLogs()
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(c.newest, prometheus.GaugeValue, float64(c.s.lastLogTime.Unix()))
+	return nil
+}
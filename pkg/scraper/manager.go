@@ -0,0 +1,160 @@
+package scraper
+
+import (
+	"context"
+	"sync"
+
+	"github.com/wbwue/FritzExporter/pkg/config"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// Manager runs a Scraper for every configured target. Apply switches to a
+// new configuration at runtime, boxes that stay keep their session and
+// collector state.
+type Manager struct {
+	logger log.Logger
+
+	mu       sync.Mutex
+	running  bool
+	scrapers map[string]*managedScraper
+}
+
+type managedScraper struct {
+	scraper *Scraper
+	// started is closed once the session of the scraper is prepared
+	started chan struct{}
+}
+
+// NewManager creates a Manager, its scrapers log with logger and the box label.
+func NewManager(logger log.Logger) *Manager {
+	return &Manager{
+		logger:   logger,
+		scrapers: make(map[string]*managedScraper),
+	}
+}
+
+// Apply replaces the scrapers with those for the targets of cfg. Scrapers
+// whose target didn't change are kept and only switch their collectors,
+// removed targets are logged out.
+func (m *Manager) Apply(cfg *config.Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	scrapers := make(map[string]*managedScraper, len(cfg.Targets))
+	for _, target := range cfg.Targets {
+		logger := log.With(m.logger, "box", target.Name)
+		old, ok := m.scrapers[target.Name]
+		if ok && old.scraper.target == target {
+			old.scraper.reconfigure(cfg)
+			if m.running {
+				old.scraper.startCallMonitor()
+			}
+			scrapers[target.Name] = old
+			delete(m.scrapers, target.Name)
+			continue
+		}
+		ms := &managedScraper{
+			scraper: NewScraper(cfg, target, logger),
+			started: make(chan struct{}),
+		}
+		scrapers[target.Name] = ms
+		if m.running {
+			level.Info(logger).Log("msg", "adding target", "url", target.URL)
+			ms.scraper.startCallMonitor()
+			go ms.start()
+		}
+	}
+	for name, old := range m.scrapers {
+		if m.running {
+			level.Info(old.scraper.logger).Log("msg", "removing target")
+			go old.stop()
+		}
+		delete(m.scrapers, name)
+	}
+	m.scrapers = scrapers
+}
+
+// Run starts the scrapers and their call monitors and stops them when ctx
+// is done.
+func (m *Manager) Run(ctx context.Context) error {
+	m.mu.Lock()
+	m.running = true
+	for _, ms := range m.scrapers {
+		ms.scraper.startCallMonitor()
+		go ms.start()
+	}
+	m.mu.Unlock()
+
+	<-ctx.Done()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.running = false
+	var wg sync.WaitGroup
+	for _, ms := range m.scrapers {
+		wg.Add(1)
+		go func(ms *managedScraper) {
+			defer wg.Done()
+			ms.stop()
+		}(ms)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (ms *managedScraper) start() {
+	ms.scraper.start()
+	close(ms.started)
+}
+
+func (ms *managedScraper) stop() {
+	<-ms.started
+	ms.scraper.stop()
+}
+
+// scraper returns the scraper of the named target, or the only one if
+// name is empty.
+func (m *Manager) scraper(name string) (*Scraper, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if name == "" && len(m.scrapers) == 1 {
+		for _, ms := range m.scrapers {
+			return ms.scraper, true
+		}
+	}
+	ms, ok := m.scrapers[name]
+	if !ok {
+		return nil, false
+	}
+	return ms.scraper, true
+}
+
+// Describe implements prometheus.Collector. It sends no descriptions, as
+// the boxes change with the configuration, which makes the Manager an
+// unchecked collector.
+func (m *Manager) Describe(ch chan<- *prometheus.Desc) {
+}
+
+// Collect implements prometheus.Collector, the boxes are scraped concurrently.
+func (m *Manager) Collect(ch chan<- prometheus.Metric) {
+	m.mu.Lock()
+	scrapers := make([]*Scraper, 0, len(m.scrapers))
+	for _, ms := range m.scrapers {
+		scrapers = append(scrapers, ms.scraper)
+	}
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, s := range scrapers {
+		wg.Add(1)
+		go func(s *Scraper) {
+			defer wg.Done()
+			s.Collect(ch)
+		}(s)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,157 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/wbwue/FritzExporter/pkg/fritz"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("mesh", false, func(s *Scraper) Collector { return newMeshCollector(s) })
+}
+
+var errMeshDisabled = errors.New("the mesh collector is disabled for this box")
+
+// meshCollector exports the mesh topology, i.e. which repeater every
+// device hangs off and how fast its uplink is.
+type meshCollector struct {
+	s          *Scraper
+	node       *prometheus.Desc
+	hops       *prometheus.Desc
+	uplinkRate *prometheus.Desc
+	uplinkMax  *prometheus.Desc
+
+	mu sync.Mutex
+	// graph is the topology of the last update, served by /mesh
+	graph *fritz.MeshGraph
+}
+
+func newMeshCollector(s *Scraper) *meshCollector {
+	labels := []string{"node", "mac", "parent", "uplink_type"}
+	return &meshCollector{
+		s:          s,
+		node:       s.newDesc("fritzbox_mesh_node_info", "Gauge showing the boxes and repeaters of the mesh", "node", "mac", "model", "role"),
+		hops:       s.newDesc("fritzbox_mesh_hops", "Gauge showing the number of hops from the device to the mesh master", labels...),
+		uplinkRate: s.newDesc("fritzbox_mesh_uplink_rate_bits_per_second", "Gauge showing the current rate of the link towards the mesh master", append(labels, "direction")...),
+		uplinkMax:  s.newDesc("fritzbox_mesh_uplink_max_rate_bits_per_second", "Gauge showing the maximum rate of the link towards the mesh master", append(labels, "direction")...),
+	}
+}
+
+func (c *meshCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.node
+	ch <- c.hops
+	ch <- c.uplinkRate
+	ch <- c.uplinkMax
+}
+
+func (c *meshCollector) Update(ch chan<- prometheus.Metric) error {
+	g, err := c.s.meshGraph()
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.graph = g
+	c.mu.Unlock()
+	c.export(g, ch)
+	return nil
+}
+
+// export sends the nodes and uplinks of g.
+func (c *meshCollector) export(g *fritz.MeshGraph, ch chan<- prometheus.Metric) {
+	name := func(uid string) string {
+		n := g.Nodes[uid]
+		return c.s.config().DeviceName(n.Name, n.MAC)
+	}
+	uids := make([]string, 0, len(g.Nodes))
+	for uid := range g.Nodes {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	// nodes of the same device, e.g. with a LAN and a WLAN interface, can
+	// share name and MAC, the one with the lowest UID is exported
+	exported := make(map[string]bool, 2*len(uids))
+	once := func(labels ...string) bool {
+		key := strings.Join(labels, "\x00")
+		if exported[key] {
+			return false
+		}
+		exported[key] = true
+		return true
+	}
+	for _, uid := range uids {
+		n := g.Nodes[uid]
+		if n.Meshed && once("node", name(uid), n.MAC, n.Model, n.Role) {
+			ch <- prometheus.MustNewConstMetric(c.node, prometheus.GaugeValue, 1, name(uid), n.MAC, n.Model, n.Role)
+		}
+		parent, ok := g.Parents[uid]
+		if !ok {
+			continue
+		}
+		uplink := g.Uplinks[uid]
+		labels := []string{name(uid), n.MAC, name(parent), uplink.Type}
+		if !once(append([]string{"uplink"}, labels...)...) {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.hops, prometheus.GaugeValue, float64(g.Hops[uid]), labels...)
+		ch <- prometheus.MustNewConstMetric(c.uplinkRate, prometheus.GaugeValue, uplink.CurDataRateRx*1000, append(labels, "rx")...)
+		ch <- prometheus.MustNewConstMetric(c.uplinkRate, prometheus.GaugeValue, uplink.CurDataRateTx*1000, append(labels, "tx")...)
+		ch <- prometheus.MustNewConstMetric(c.uplinkMax, prometheus.GaugeValue, uplink.MaxDataRateRx*1000, append(labels, "rx")...)
+		ch <- prometheus.MustNewConstMetric(c.uplinkMax, prometheus.GaugeValue, uplink.MaxDataRateTx*1000, append(labels, "tx")...)
+	}
+}
+
+// cachedGraph returns the mesh topology of the mesh collector, which is
+// only fetched from the box if the cached one expired.
+func (s *Scraper) cachedGraph() (*fritz.MeshGraph, error) {
+	for _, cc := range s.collectorList() {
+		c, ok := cc.collector.(*meshCollector)
+		if !ok {
+			continue
+		}
+		if err := cc.refresh(); err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.graph, nil
+	}
+	return nil, errMeshDisabled
+}
+
+// ServeMesh answers /mesh?target=<box>&format=json|dot with the mesh
+// topology of a configured box as cached by its mesh collector, target may
+// be omitted with a single box.
+func (m *Manager) ServeMesh(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	s, ok := m.scraper(params.Get("target"))
+	if !ok {
+		http.Error(w, "unknown target, give the name of a configured box", http.StatusBadRequest)
+		return
+	}
+	g, err := s.cachedGraph()
+	if errors.Is(err, errMeshDisabled) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	switch params.Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(g)
+	case "dot":
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+		w.Write([]byte(g.DOT()))
+	default:
+		http.Error(w, "format must be json or dot", http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,82 @@
+package scraper
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/wbwue/FritzExporter/pkg/config"
+	"github.com/wbwue/FritzExporter/pkg/fritz"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestMeshExport(t *testing.T) {
+	data, err := os.ReadFile("../fritz/testdata/meshlist.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := fritz.DecodeMeshList(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newMeshCollector(&Scraper{cfg: config.NewConfig(), target: config.Target{Name: "main"}})
+	ch := make(chan prometheus.Metric, 64)
+	c.export(g, ch)
+	close(ch)
+
+	names := map[*prometheus.Desc]string{c.node: "node", c.hops: "hops", c.uplinkRate: "rate", c.uplinkMax: "max"}
+	counts := make(map[string]int)
+	values := make(map[string]float64)
+	for m := range ch {
+		var d dto.Metric
+		if err := m.Write(&d); err != nil {
+			t.Fatal(err)
+		}
+		labels := []string{names[m.Desc()]}
+		for _, l := range d.GetLabel() {
+			if l.GetName() != "box" {
+				labels = append(labels, l.GetName()+"="+l.GetValue())
+			}
+		}
+		sort.Strings(labels[1:])
+		key := strings.Join(labels, ",")
+		counts[key]++
+		values[key] = d.GetGauge().GetValue()
+	}
+	for key, n := range counts {
+		if n > 1 {
+			t.Errorf("%s exported %d times", key, n)
+		}
+	}
+
+	for key, want := range map[string]float64{
+		"node,mac=AA:00:00:00:00:01,model=FRITZ!Box 7590,node=fritz.box,role=master":              1,
+		"node,mac=AA:00:00:00:00:02,model=FRITZ!Repeater 2400,node=repeater,role=slave":           1,
+		"hops,mac=AA:00:00:00:00:02,node=repeater,parent=fritz.box,uplink_type=WLAN":              1,
+		"hops,mac=AA:00:00:00:00:06,node=repeater 2,parent=fritz.box,uplink_type=WLAN":            1,
+		"hops,mac=AA:00:00:00:00:03,node=laptop,parent=repeater,uplink_type=WLAN":                 2,
+		"rate,direction=rx,mac=AA:00:00:00:00:02,node=repeater,parent=fritz.box,uplink_type=WLAN": 650e6,
+		"max,direction=tx,mac=AA:00:00:00:00:03,node=laptop,parent=repeater,uplink_type=WLAN":     144e6,
+	} {
+		got, ok := values[key]
+		if !ok {
+			t.Errorf("%s not exported", key)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+	// 3 meshed nodes, 4 uplinks of which the laptop's two are the same
+	if len(counts) != 3+3*5 {
+		t.Errorf("%d series, want %d", len(counts), 3+3*5)
+	}
+	for key := range counts {
+		if strings.Contains(key, "printer") {
+			t.Errorf("unreachable node exported: %s", key)
+		}
+	}
+}
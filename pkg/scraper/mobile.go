@@ -0,0 +1,95 @@
+package scraper
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/wbwue/FritzExporter/pkg/fritz"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+func init() {
+	registerCollector("mobile", false, func(s *Scraper) Collector { return newMobileCollector(s) })
+}
+
+// mobileCollector exports the LTE/5G connection of boxes with a mobile
+// modem, either as primary WAN or as fallback for another line.
+type mobileCollector struct {
+	s         *Scraper
+	connected *prometheus.Desc
+	rsrp      *prometheus.Desc
+	rsrq      *prometheus.Desc
+	sinr      *prometheus.Desc
+	rssi      *prometheus.Desc
+	info      *prometheus.Desc
+	wanActive *prometheus.Desc
+}
+
+func newMobileCollector(s *Scraper) *mobileCollector {
+	return &mobileCollector{
+		s:         s,
+		connected: s.newDesc("fritzbox_mobile_connected", "Gauge showing whether the mobile modem has a data connection"),
+		rsrp:      s.newDesc("fritzbox_mobile_rsrp_dbm", "Gauge showing the reference signal received power of the mobile connection"),
+		rsrq:      s.newDesc("fritzbox_mobile_rsrq_db", "Gauge showing the reference signal received quality of the mobile connection"),
+		sinr:      s.newDesc("fritzbox_mobile_sinr_db", "Gauge showing the signal to interference plus noise ratio of the mobile connection"),
+		rssi:      s.newDesc("fritzbox_mobile_rssi_dbm", "Gauge showing the received signal strength of the mobile connection"),
+		info:      s.newDesc("fritzbox_mobile_info", "Gauge showing the technology, band, cell and operator of the mobile connection", "technology", "band", "cell_id", "operator", "status"),
+		wanActive: s.newDesc("fritzbox_wan_active", "Gauge showing which WAN carries the internet traffic", "wan"),
+	}
+}
+
+func (c *mobileCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.connected
+	ch <- c.rsrp
+	ch <- c.rsrq
+	ch <- c.sinr
+	ch <- c.rssi
+	ch <- c.info
+	ch <- c.wanActive
+}
+
+func (c *mobileCollector) Update(ch chan<- prometheus.Metric) error {
+	m, err := c.s.mobileInfo()
+	if err != nil {
+		return err
+	}
+	// the mobile connection carries the traffic if it is the access type of
+	// the box or the primary line is down while it is connected
+	var link wanLinkInfo
+	err = c.s.linkProperties(&link)
+	linkKnown := err == nil
+	if errors.Is(err, fritz.ErrNotSupported) {
+		level.Debug(c.s.logger).Log("msg", "WAN link state not supported by box", "err", err)
+	} else if err != nil {
+		return err
+	}
+
+	connected := 0.0
+	if m.Connected() {
+		connected = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.connected, prometheus.GaugeValue, connected)
+	for desc, value := range map[*prometheus.Desc]*float64{c.rsrp: m.RSRP, c.rsrq: m.RSRQ, c.sinr: m.SINR, c.rssi: m.RSSI} {
+		if value != nil {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, *value)
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, m.Technology, m.Band, m.CellID, m.Operator, m.Status)
+
+	if !linkKnown {
+		return nil
+	}
+	mobileActive, primaryActive := 0.0, 0.0
+	primaryUp := link.linkStatus == "Up"
+	if strings.Contains(link.wanAccessType, "Mobile") || (m.Connected() && !primaryUp) {
+		mobileActive = 1
+	} else if primaryUp {
+		primaryActive = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.wanActive, prometheus.GaugeValue, mobileActive, "mobile")
+	ch <- prometheus.MustNewConstMetric(c.wanActive, prometheus.GaugeValue, primaryActive, "primary")
+	return nil
+}
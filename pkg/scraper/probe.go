@@ -0,0 +1,178 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/wbwue/FritzExporter/pkg/config"
+	"github.com/wbwue/FritzExporter/pkg/fritz"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// Prober answers /probe?target=<box>&module=<auth module> requests with a
+// synchronous scrape of the box, in the style of the blackbox_exporter.
+// Sessions are kept between probes, so every probe doesn't cause a login.
+type Prober struct {
+	logger log.Logger
+
+	mu       sync.Mutex
+	cfg      *config.Config
+	sessions map[config.Target]*probeSession
+}
+
+type probeSession struct {
+	session  *fritz.Session
+	lastUsed time.Time
+}
+
+const (
+	// probeSessionTTL is how long the session of a target is kept without probes
+	probeSessionTTL = 10 * time.Minute
+	// maxProbeSessions limits the sessions kept, the least recently used is
+	// logged out first
+	maxProbeSessions = 64
+)
+
+func NewProber(cfg *config.Config, logger log.Logger) *Prober {
+	return &Prober{
+		cfg:      cfg,
+		logger:   logger,
+		sessions: make(map[config.Target]*probeSession),
+	}
+}
+
+// SetConfig makes later probes use cfg for targets and auth modules.
+func (p *Prober) SetConfig(cfg *config.Config) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cfg = cfg
+}
+
+func (p *Prober) config() *config.Config {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cfg
+}
+
+func (p *Prober) session(target config.Target) *fritz.Session {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	p.evict(now)
+	ps, ok := p.sessions[target]
+	if !ok {
+		if len(p.sessions) >= maxProbeSessions {
+			p.evictOldest()
+		}
+		ps = &probeSession{session: fritz.NewSession(target.URL, target.Username, target.Password)}
+		p.sessions[target] = ps
+	}
+	ps.lastUsed = now
+	return ps.session
+}
+
+// Run logs out idle sessions until ctx is done, then all of them.
+func (p *Prober) Run(ctx context.Context) error {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			p.mu.Lock()
+			p.evict(now)
+			p.mu.Unlock()
+		case <-ctx.Done():
+			p.mu.Lock()
+			sessions := p.sessions
+			p.sessions = make(map[config.Target]*probeSession)
+			p.mu.Unlock()
+			for _, ps := range sessions {
+				ps.session.Logout()
+			}
+			return nil
+		}
+	}
+}
+
+// evict logs out the sessions idle for longer than probeSessionTTL, it must
+// be called with mu held.
+func (p *Prober) evict(now time.Time) {
+	for target, ps := range p.sessions {
+		if now.Sub(ps.lastUsed) > probeSessionTTL {
+			p.logout(target)
+		}
+	}
+}
+
+func (p *Prober) evictOldest() {
+	var oldest config.Target
+	var oldestUsed time.Time
+	for target, ps := range p.sessions {
+		if oldestUsed.IsZero() || ps.lastUsed.Before(oldestUsed) {
+			oldest, oldestUsed = target, ps.lastUsed
+		}
+	}
+	p.logout(oldest)
+}
+
+// logout removes the session of target and logs it out in the background,
+// a probe still using it logs in again.
+func (p *Prober) logout(target config.Target) {
+	ps, ok := p.sessions[target]
+	if !ok {
+		return
+	}
+	delete(p.sessions, target)
+	go func() {
+		if err := ps.session.Logout(); err != nil {
+			level.Debug(p.logger).Log("msg", "probe session logout failed", "box", target.Name, "err", err)
+		}
+	}()
+}
+
+func (p *Prober) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	if params.Get("target") == "" {
+		http.Error(w, "target parameter is missing", http.StatusBadRequest)
+		return
+	}
+	cfg := p.config()
+	target, err := cfg.ProbeTarget(params.Get("target"), params.Get("module"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logger := log.With(p.logger, "box", target.Name)
+
+	probeSuccess := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_success",
+		Help: "Displays whether or not the probe was a success",
+	})
+	probeDuration := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_duration_seconds",
+		Help: "Returns how long the probe took to complete in seconds",
+	})
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(probeSuccess, probeDuration)
+
+	// the cache only has to outlive this request, Scrape fills it
+	s := newScraper(cfg, target, p.session(target), time.Hour, logger)
+	start := time.Now()
+	err = s.Scrape()
+	probeDuration.Set(time.Since(start).Seconds())
+	if err != nil {
+		level.Warn(logger).Log("msg", "probe failed", "err", err)
+	} else {
+		probeSuccess.Set(1)
+	}
+	registry.MustRegister(s)
+
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
@@ -1,328 +1,413 @@
 package scraper
 
 import (
-	"context"
-	"crypto/md5"
-	"encoding/hex"
-	"encoding/xml"
 	"errors"
-	"io"
-	"io/ioutil"
-	"net/http"
+	"fmt"
 	"net/url"
 	"os"
-	"regexp"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
-	"unicode/utf16"
 
 	"github.com/wbwue/FritzExporter/pkg/config"
 	"github.com/wbwue/FritzExporter/pkg/fritz"
 	"github.com/wbwue/FritzExporter/pkg/loki"
 
-	"github.com/ndecker/fritzbox_exporter/fritzbox_upnp"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 )
 
-var (
-	loginSid    *string
-	lastLogTime *time.Time
-
-	LanDevicesOnline = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "fritzbox_lan_devices_online",
-		Help: "Gauge showing online state of device",
-	}, []string{"name", "ip", "mac", "dev_type"})
-	LanDevicesActive = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "fritzbox_lan_devices_active",
-		Help: "Gauge showing active state of device",
-	}, []string{"name", "ip", "mac", "dev_type"})
-	LanDevicesSpeed = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "fritzbox_lan_devices_speed",
-		Help: "Gauge showing speed of device",
-	}, []string{"name", "ip", "mac", "dev_type"})
-	WlanDeviceSignal = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "fritzbox_wlan_devices_signal",
-		Help: "Gauge showing signal strength of wifi devices",
-	}, []string{"name", "ip", "mac", "dev_type"})
-	WlanDeviceSpeed = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "fritzbox_wlan_devices_speed",
-		Help: "Gauge showing current speed of wifi devices",
-	}, []string{"name", "ip", "mac", "dev_type", "direction"})
-	WlanDeviceSpeedMax = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "fritzbox_wlan_devices_speed_max",
-		Help: "Gauge showing maximum speed of wifi devices",
-	}, []string{"name", "ip", "mac", "dev_type", "direction"})
-	WlanDeviceInfo = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "fritzbox_wlan_devices_info",
-		Help: "Gauge showing maximum speed of wifi devices",
-	}, []string{"name", "ip", "mac", "dev_type", "band", "standard", "encryption"})
-
-	InternetDownstreamSpeed = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "fritzbox_internet_downstream_current",
-		Help: "Gauge showing latest internet downstream speed",
-	}, []string{"type"})
-	InternetUpstreamSpeed = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "fritzbox_internet_upstream_current",
-		Help: "Gauge showing latest internet upstream speed",
-	}, []string{"type"})
-)
-
 type Scraper struct {
-	cfg              *config.Config
-	logger           log.Logger
-	deviceband       map[string]prometheus.Labels
-	upnpServicesRoot *fritzbox_upnp.Root
-	connectionInfos  *prometheus.Labels
-	logPusher        loki.Pusher
+	cfg         *config.Config
+	target      config.Target
+	logger      log.Logger
+	session     *fritz.Session
+	logPusher   loki.Pusher
+	savedSID    string
+	lastLogTime time.Time
+	collectors  []*cachedCollector
+
+	upDesc                *prometheus.Desc
+	loginBlockedDesc      *prometheus.Desc
+	loginsDesc            *prometheus.Desc
+	collectorDurationDesc *prometheus.Desc
+	collectorErrorsDesc   *prometheus.Desc
+	lastSuccessDesc       *prometheus.Desc
+
+	mu       sync.Mutex
+	devTypes map[string]string
+	// lastDeviceInfo is the last answer of GetInfo of the DeviceInfo service
+	lastDeviceInfo        map[string]string
+	lastDeviceInfoFetched time.Time
+}
+
+// deviceInfoMaxAge is how long the firmware and link collectors share an
+// answer of GetInfo of the DeviceInfo service, both need it every update.
+const deviceInfoMaxAge = 10 * time.Second
+
+// NewScraper creates a Scraper for a single box. It implements
+// prometheus.Collector, several scrapers can be registered side by side as
+// the box label keeps their metrics apart.
+func NewScraper(config *config.Config, target config.Target, logger log.Logger) *Scraper {
+	session := fritz.NewSession(target.URL, target.Username, target.Password)
+	return newScraper(config, target, session, 0, logger)
 }
 
-func NewScraper(config *config.Config, logger log.Logger) *Scraper {
-	return &Scraper{
-		cfg:        config,
-		logger:     logger,
-		deviceband: make(map[string]prometheus.Labels),
-		logPusher:  loki.New(config.LokiURL),
+// newScraper creates a Scraper using the given session. A ttl above 0
+// overrides the cache durations of all collectors.
+func newScraper(config *config.Config, target config.Target, session *fritz.Session, ttl time.Duration, logger log.Logger) *Scraper {
+	s := &Scraper{
+		cfg:         config,
+		target:      target,
+		logger:      logger,
+		session:     session,
+		logPusher:   newLogPusher(config, target, logger),
+		lastLogTime: time.Unix(0, 0),
+		devTypes:    make(map[string]string),
+	}
+	s.upDesc = s.newDesc("fritzbox_up", "Gauge showing whether the exporter is logged in and the last scrape succeeded")
+	s.loginBlockedDesc = s.newDesc("fritzbox_login_blocked_seconds", "Gauge showing how long the next login attempt is held back after a failed login")
+	s.loginsDesc = s.newDesc("fritz_exporter_logins_total", "Counter of login attempts by result", "result")
+	s.collectorDurationDesc = s.newDesc("fritz_exporter_collector_duration_seconds", "Gauge showing how long the last update of a collector took", "collector")
+	s.collectorErrorsDesc = s.newDesc("fritz_exporter_collector_errors_total", "Counter of failed collector updates by reason", "collector", "reason")
+	s.lastSuccessDesc = s.newDesc("fritz_exporter_last_successful_scrape_timestamp", "Gauge showing the time of the last successful update of a collector", "collector")
+	s.collectors = s.configureCollectors(config, ttl)
+	return s
+}
+
+func newLogPusher(config *config.Config, target config.Target, logger log.Logger) loki.Pusher {
+	logPusher := loki.New(config.LokiURL, logger)
+	for name, value := range config.LokiLabels {
+		logPusher.Labels[name] = value
+	}
+	logPusher.Labels["box"] = target.Name
+	return logPusher
+}
+
+// configureCollectors returns the collectors enabled in config. Those the
+// scraper runs already are kept with their state and only get the new
+// interval, the others are created. A ttl above 0 overrides the cache
+// durations of all collectors.
+func (s *Scraper) configureCollectors(config *config.Config, ttl time.Duration) []*cachedCollector {
+	current := s.collectorList()
+	running := make(map[string]*cachedCollector, len(current))
+	for _, c := range current {
+		running[c.name] = c
+	}
+	var collectors []*cachedCollector
+	for _, name := range CollectorNames() {
+		enabled, interval := factories[name].enabledByDefault, config.CacheTTL
+		if c, ok := config.Collectors[name]; ok {
+			enabled = c.Enabled
+			if c.Interval > 0 {
+				interval = c.Interval
+			}
+		}
+		if ttl > 0 {
+			interval = ttl
+		}
+		if !enabled {
+			continue
+		}
+		if c, ok := running[name]; ok {
+			c.setTTL(interval)
+			collectors = append(collectors, c)
+			continue
+		}
+		collector := factories[name].create(s)
+		if collector == nil {
+			continue
+		}
+		collectors = append(collectors, newCachedCollector(name, collector, interval))
+	}
+	return collectors
+}
+
+// reconfigure switches the scraper of an unchanged target to config.
+// Collectors that stay enabled keep their state, so counters like the
+// link byte totals continue across reloads. A call monitor no longer
+// needed is stopped once no collection can reach it anymore.
+func (s *Scraper) reconfigure(config *config.Config) {
+	logPusher := newLogPusher(config, s.target, s.logger)
+	previous := s.callMonitor()
+	collectors := s.configureCollectors(config, 0)
+	monitor := callMonitorOf(collectors)
+	if monitor != nil {
+		monitor.setLogPusher(logPusher)
+	}
+
+	s.mu.Lock()
+	s.cfg = config
+	s.logPusher = logPusher
+	s.collectors = collectors
+	s.mu.Unlock()
+
+	if previous != nil && previous != monitor {
+		previous.stop()
 	}
 }
 
-func (s *Scraper) Run(ctx context.Context) error {
-	zeroTime := time.Unix(0, 0)
-	lastLogTime = &zeroTime
+// config returns the configuration the scraper currently runs with.
+func (s *Scraper) config() *config.Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cfg
+}
+
+func (s *Scraper) pusher() loki.Pusher {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logPusher
+}
+
+func (s *Scraper) collectorList() []*cachedCollector {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.collectors
+}
 
-	if s.cfg.LogPath != "" {
-		f, err := os.Create(s.cfg.LogPath)
+// callMonitor returns the call monitor of the callmonitor collector, nil
+// if it isn't enabled.
+func (s *Scraper) callMonitor() *callMonitor {
+	return callMonitorOf(s.collectorList())
+}
+
+// newDesc creates a metric description carrying the box label.
+func (s *Scraper) newDesc(name string, help string, labels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(name, help, labels, prometheus.Labels{"box": s.target.Name})
+}
+
+// start truncates the log file and restores or opens the session, the
+// metrics are fetched when Prometheus asks for them.
+func (s *Scraper) start() {
+	if s.target.LogPath != "" {
+		f, err := os.Create(s.target.LogPath)
 		if err != nil {
 			level.Warn(s.logger).Log("Failed to open log file")
 		} else {
 			f.Close()
 		}
 	}
+	if s.target.StateFile != "" {
+		reused, err := s.session.LoadState(s.target.StateFile)
+		if err != nil {
+			level.Warn(s.logger).Log("msg", "Failed to restore session", "state_file", s.target.StateFile, "err", err)
+		} else if reused {
+			level.Info(s.logger).Log("msg", "reusing session from state file", "state_file", s.target.StateFile)
+			s.savedSID = s.session.SID()
+		}
+	}
 	err := s.loadServices()
 	if err != nil {
-		level.Warn(s.logger).Log(err)
-		return err
+		level.Warn(s.logger).Log("msg", "Failed to load TR-064 services", "err", err)
 	}
-	for {
-		select {
-		case <-ctx.Done():
+	if !s.session.LoggedIn() {
+		// failures are retried by the session with backoff on the next collection
+		s.Login()
+	}
+	s.saveState()
+}
 
-			return nil
-		default:
-			if loginSid == nil {
-				err := s.Login()
-				if err != nil {
-					level.Warn(s.logger).Log("Failed to login")
-					return err
-				}
-			}
-			err := s.Scrape()
-			if err != nil {
-				level.Warn(s.logger).Log("Failed to scrape")
-			}
-			time.Sleep(15 * time.Second)
+// stop closes the session unless it is kept in a state file for the next
+// start, and disconnects from the call monitor.
+func (s *Scraper) stop() {
+	if m := s.callMonitor(); m != nil {
+		m.stop()
+	}
+	if s.target.StateFile == "" {
+		s.Logout()
+	}
+}
+
+// startCallMonitor connects to the call monitor if the callmonitor
+// collector is enabled, it's a no-op if the monitor runs already.
+func (s *Scraper) startCallMonitor() {
+	if m := s.callMonitor(); m != nil {
+		m.start()
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (s *Scraper) Describe(ch chan<- *prometheus.Desc) {
+	ch <- s.upDesc
+	ch <- s.loginBlockedDesc
+	ch <- s.loginsDesc
+	ch <- s.collectorDurationDesc
+	ch <- s.collectorErrorsDesc
+	ch <- s.lastSuccessDesc
+	for _, c := range s.collectorList() {
+		c.collector.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector, collectors whose cached data
+// expired query the box.
+func (s *Scraper) Collect(ch chan<- prometheus.Metric) {
+	s.collect(ch, false)
+}
+
+// Scrape updates all collectors regardless of their cache age.
+func (s *Scraper) Scrape() error {
+	ch := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+	err := s.collect(ch, true)
+	close(ch)
+	<-done
+	return err
+}
+
+func (s *Scraper) collect(ch chan<- prometheus.Metric, force bool) error {
+	var scrapeErr error
+	for _, c := range s.collectorList() {
+		err := c.collect(ch, force)
+		if errors.Is(err, fritz.ErrNotSupported) {
+			level.Debug(s.logger).Log("msg", "collector not supported by box", "collector", c.name, "err", err)
+		} else if err != nil {
+			level.Warn(s.logger).Log("msg", "collector failed", "collector", c.name, "err", err)
+			scrapeErr = fmt.Errorf("collector %s: %w", c.name, err)
+		}
+		stats := c.stats()
+		ch <- prometheus.MustNewConstMetric(s.collectorDurationDesc, prometheus.GaugeValue, stats.duration.Seconds(), c.name)
+		for reason, count := range stats.errors {
+			ch <- prometheus.MustNewConstMetric(s.collectorErrorsDesc, prometheus.CounterValue, count, c.name, reason)
+		}
+		if !stats.lastSuccess.IsZero() {
+			ch <- prometheus.MustNewConstMetric(s.lastSuccessDesc, prometheus.GaugeValue, float64(stats.lastSuccess.Unix()), c.name)
 		}
 	}
+	for result, count := range s.session.LoginCounts() {
+		ch <- prometheus.MustNewConstMetric(s.loginsDesc, prometheus.CounterValue, float64(count), result)
+	}
+	up := 0.0
+	if scrapeErr == nil && s.session.LoggedIn() {
+		up = 1
+	}
+	ch <- prometheus.MustNewConstMetric(s.upDesc, prometheus.GaugeValue, up)
+	blocked := time.Until(s.session.BlockedUntil()).Seconds()
+	if blocked < 0 {
+		blocked = 0
+	}
+	ch <- prometheus.MustNewConstMetric(s.loginBlockedDesc, prometheus.GaugeValue, blocked)
+	s.saveState()
+	return scrapeErr
 }
 
 func (s *Scraper) Login() error {
 	level.Debug(s.logger).Log("logging in")
-	uri := "login_sid.lua"
-	url := s.cfg.FritzBoxURL + uri
-	resp, err := http.Get(url)
+	err := s.session.Login()
 	if err != nil {
 		level.Warn(s.logger).Log("Error logging in", err)
 		return err
-	} else {
-		defer resp.Body.Close()
-		body, _ := ioutil.ReadAll(resp.Body)
-		// expected: <?xml version=\"1.0\" encoding=\"utf-8\"?><SessionInfo><SID>0000000000000000</SID><Challenge>abababababa</Challenge><BlockTime>0</BlockTime><Rights></Rights></SessionInfo>
-		c := &fritz.LoginChallenge{}
-		err := xml.Unmarshal([]byte(body), &c)
-		if err != nil {
-			level.Warn(s.logger).Log("Error parsing xml", err)
-			return err
-		}
-
-		if c.SID == "0000000000000000" {
-			//cpstr := strings.ToLower(c.Challenge + "-" + s.cfg.Password)
-			cpstr := c.Challenge + "-" + s.cfg.Password
-			md5sum := GetMD5Hash(cpstr)
-			responseCode := c.Challenge + "-" + md5sum
-			resp, err := http.Get(url + "?user=" + s.cfg.Username + "&response=" + responseCode)
-			if err != nil {
-				level.Warn(s.logger).Log("Error on presenting response", err)
-				return err
-			} else {
-				defer resp.Body.Close()
-				body, _ := ioutil.ReadAll(resp.Body)
-				// expected: <?xml version=\"1.0\" encoding=\"utf-8\"?><SessionInfo><SID>0000000000000000</SID><Challenge>abababababa</Challenge><BlockTime>0</BlockTime><Rights></Rights></SessionInfo>
-				level.Debug(s.logger).Log("Response", body)
-				err := xml.Unmarshal([]byte(body), &c)
-				if err != nil {
-					level.Warn(s.logger).Log("Error parsing xml", err)
-					return err
-				}
-				if c.SID == "0000000000000000" {
-					level.Warn(s.logger).Log("Credentials invalid")
-					return errors.New("Login failed")
-				} else {
-					loginSid = &c.SID
-				}
-			}
-		}
-		time.Sleep(time.Duration(c.BlockTime) * time.Second)
+	}
+	level.Debug(s.logger).Log("msg", "logged in", "rights", fmt.Sprint(s.session.Rights()))
+	if !s.session.Rights().CanRead("BoxAdmin") {
+		level.Warn(s.logger).Log("msg", "user lacks FRITZ!Box settings permission, some metrics will be missing")
 	}
 	return nil
 }
 
-func GetMD5Hash(text string) string {
-	hasher := md5.New()
-	codes := utf16.Encode([]rune(text))
-	b := make([]byte, len(codes)*2)
-	for i, r := range codes {
-		b[i*2] = byte(r)
-		b[i*2+1] = byte(r >> 8)
+// saveState persists the SID whenever the session got renewed, so the next
+// start can continue without a new login.
+func (s *Scraper) saveState() {
+	if s.target.StateFile == "" || s.session.SID() == s.savedSID {
+		return
 	}
-	_, err := hasher.Write([]byte(b))
+	err := s.session.SaveState(s.target.StateFile)
 	if err != nil {
-		print("error")
+		level.Warn(s.logger).Log("msg", "Failed to write state file", "state_file", s.target.StateFile, "err", err)
+		return
 	}
-	return hex.EncodeToString(hasher.Sum(nil))
+	s.savedSID = s.session.SID()
 }
 
-func (s *Scraper) Scrape() error {
-	level.Debug(s.logger).Log("lastLogTime", lastLogTime.Format(time.RFC3339))
+// Logout closes the session on the box, so restarts don't leave dangling sessions.
+func (s *Scraper) Logout() {
+	err := s.session.Logout()
+	if err != nil {
+		level.Warn(s.logger).Log("msg", "failed to log out", "err", err)
+		return
+	}
+	level.Info(s.logger).Log("msg", "logged out")
+}
 
-	landevices, _ := s.query("query.lua", "network=landevice:settings/landevice/list(name,ip,mac,UID,dhcp,wlan,ethernet,active,wakeup,deleteable,source,online,speed,guest,url,devtype)", "GET", nil)
+// lanDevices queries the list of all devices known to the box.
+func (s *Scraper) lanDevices() (*fritz.LanDevices, error) {
+	landevices, err := s.query("query.lua", "network=landevice:settings/landevice/list(name,ip,mac,UID,dhcp,wlan,ethernet,active,wakeup,deleteable,source,online,speed,guest,url,devtype)", "GET", nil)
+	if err != nil {
+		return nil, err
+	}
 	level.Debug(s.logger).Log(landevices)
 	l := &fritz.LanDevices{}
-	err := l.Decode(landevices)
+	err = l.Decode(landevices)
 	if err != nil {
-		// usually login expired
-		err := s.Login()
-		if err != nil {
-			level.Warn(s.logger).Log("Error", err)
-		}
-		landevices, _ := s.query("query.lua", "network=landevice:settings/landevice/list(name,ip,mac,UID,dhcp,wlan,ethernet,active,wakeup,deleteable,source,online,speed,guest,url,devtype)", "GET", nil)
-		err = l.Decode(landevices)
-		if err != nil {
-			level.Warn(s.logger).Log("Error", err)
-		}
-	} else {
-		for _, v := range l.Network {
-			// get specific infos
-			devType := "N/A"
-			active, _ := strconv.ParseFloat(v.Active, 64)
-			online, _ := strconv.ParseFloat(v.Online, 64)
-			speed, _ := strconv.ParseFloat(v.Speed, 64)
-			if online == 1 {
-				fd, err := s.deviceSpecificData(v.UID)
-				if err == nil {
-					devType = fd.DevType
-					if fd.DevType == "wlan" {
-						labels := prometheus.Labels{
-							"name":     v.Name,
-							"ip":       v.IP,
-							"mac":      v.Mac,
-							"dev_type": fd.DevType,
-						}
-						WlanDeviceSignal.With(labels).Set(fd.Wlan.Rssi)
-						labels["direction"] = "tx"
-						WlanDeviceSpeed.With(labels).Set(fd.Wlan.Speed)
-						WlanDeviceSpeedMax.With(labels).Set(fd.Wlan.SpeedTxMax)
-						labels["direction"] = "rx"
-						WlanDeviceSpeed.With(labels).Set(fd.Wlan.SpeedRx)
-						WlanDeviceSpeedMax.With(labels).Set(fd.Wlan.SpeedRxMax)
-
-						delete(labels, "direction")
-						labels["standard"] = fd.Wlan.WlanStandard
-						labels["band"] = fd.Wlan.Band
-						labels["encryption"] = fd.Wlan.Encryption
-						oldLabels := s.deviceband[v.Name]
-						WlanDeviceInfo.Delete(oldLabels)
-						WlanDeviceInfo.With(labels).Set(1)
-						s.deviceband[v.Name] = labels
-					}
-				}
-			}
-			LanDevicesActive.WithLabelValues(v.Name, v.IP, v.Mac, devType).Set(active)
-			LanDevicesOnline.WithLabelValues(v.Name, v.IP, v.Mac, devType).Set(online)
-			LanDevicesSpeed.WithLabelValues(v.Name, v.IP, v.Mac, devType).Set(speed)
-		}
-	}
-
-	// Traffic Monitor is changed quite a lot since 7.57, haven't figured out yet, how to best get the data out of it, see Result below
-
-	//old
-	//trafficmon, _ := s.query("internet/inetstat_monitor.lua", "action=get_graphic&myXhr=1&xhr=1&useajay=1", "GET", nil)
-	//new
-	//	tmd := url.Values{}
-	//	tmd.Set("page", "netMoni")
-	//	tmd.Set("xhrId", "updateGraphs")
-	//	tmd.Set("useajax", "1")
-	//	trafficmon, _ := s.query("data.lua", "lang=de&xhr=1", "POST", tmd)
-	//	// Result:
-	//	// {"pid":"netMoni","hide":{"shareUsb":true,"liveTv":true,"dectRdio":true,"rrd":true,"rss":true,"ssoSet":true,"dectMail":true,"mobile":true,"liveImg":true},"timeTillLogout":"1200","time":[],"data":{"show_guest":true,"sync_groups":[{"us_bps_curr_max":21983,"us_default_bps_curr":[14488,3593,7839,21655,2771,8319,15221,2849,10985,17718,15000,9030,12844,4512,8269,16119,2282,7630,12540,2963],"ds_bps_max":29492068,"_node":"sg0","mode":"CABLE","ds_mc_bps_curr":[0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0],"ds_bps_curr":[107073,4251,3578,462893,2855,4297,104583,2897,331200,8155,346743,4855,3908,4212,4981,13479,3152,3983,3522,2952],"us_bps_max":539444,"dynamic":true,"us_realtime_bps_curr":[292,321,292,328,292,291,292,319,291,352,409,329,363,291,291,386,292,292,320,291],"downstream":3600000,"upstream":384000,"name":"sync_cable","guest_us_bps":[0],"ds_guest_bps_curr":[0,0,0,44,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0],"us_background_bps_curr":[0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0],"ds_bps_curr_max":462893,"us_important_bps_curr":[0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0]}]},"sid":"..."}
-	//	level.Info(s.logger).Log("trafficmon", trafficmon)
-	//	t, err := fritz.DecodeTrafficMonitoringData(trafficmon)
-	//	if err != nil {
-	//		level.Warn(s.logger).Log("Error", err)
-	//	} else {
-	//		InternetDownstreamSpeed.WithLabelValues("internet").Set(t.DownstreamInternet[1])
-	//		InternetDownstreamSpeed.WithLabelValues("media").Set(t.DownstreamMedia[1])
-	//		if len(t.DownstreamGuest) > 0 {
-	//			InternetDownstreamSpeed.WithLabelValues("guest").Set(t.DownstreamGuest[1])
-	//		}
-	//		InternetUpstreamSpeed.WithLabelValues("realtime").Set(t.UpstreamRealtime[1])
-	//		InternetUpstreamSpeed.WithLabelValues("high").Set(t.UpstreamHighPriority[1])
-	//		InternetUpstreamSpeed.WithLabelValues("default").Set(t.UpstreamDefaultPriority[1])
-	//		InternetUpstreamSpeed.WithLabelValues("low").Set(t.UpstreamLowPriority[1])
-	//		if len(t.UpstreamGuest) > 0 {
-	//			InternetDownstreamSpeed.WithLabelValues("guest").Set(t.UpstreamGuest[1])
-	//		}
-	//
-	//		level.Debug(s.logger).Log("traffic", t.Mode, "downstream", t.DownstreamCurrentMax, "upstream", t.UpstreamCurrentMax)
-	//
-	//	}
+		return nil, fmt.Errorf("decoding lan devices: %w", err)
+	}
+	return l, nil
+}
 
-	out, err := s.query("internet/inetstat_counter.lua", "csv=", "GET", nil)
-	if err != nil {
-		//ignore for now
-	} else {
-		level.Debug(s.logger).Log("inetstat-counter", out)
+// devType returns the connection type last seen for the device with the
+// given UID, device details are only fetched for online devices.
+func (s *Scraper) devType(UID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	devType, ok := s.devTypes[UID]
+	if !ok {
+		return "N/A"
 	}
-	//s.connectionInfos = &newLabels
-	//	ConnectionInfo.With(newLabels).Set(1)
-	//out, err := s.query("internet/inetstat_counter.lua", "csv=", "GET", nil)
-	//if err != nil {
-	//	//ignore for now
-	//} else {
-	//	level.Info(s.logger).Log("inetstat-counter", out)
-	//}
+	return devType
+}
 
-	//s.generalInfo()
-	//systemstatus, _ := s.query("cgi-bin/system_status","")
-	//wlan, _ := s.query("data.lua","xhr=1&xhrId=wlanDevices&useajax=1&no_siderenew=&lang=de")
+func (s *Scraper) setDevType(UID string, devType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.devTypes[UID] = devType
+}
 
-	if s.cfg.LogPath != "" {
-		s.queryLogs()
+// trafficMonitoring queries the online monitor, the format changed
+// considerably with FRITZ!OS 7.57 and older versions are not supported.
+func (s *Scraper) trafficMonitoring() ([]fritz.TrafficMonitoringData, error) {
+	tmd := url.Values{}
+	tmd.Set("page", "netMoni")
+	tmd.Set("xhrId", "updateGraphs")
+	tmd.Set("useajax", "1")
+	trafficmon, err := s.query("data.lua", "lang=de&xhr=1", "POST", tmd)
+	if err != nil {
+		return nil, err
+	}
+	level.Debug(s.logger).Log("trafficmon", trafficmon)
+	t, err := fritz.DecodeTrafficMonitoringData(trafficmon)
+	if err != nil {
+		return nil, fmt.Errorf("decoding traffic monitor: %w", err)
 	}
+	return t, nil
+}
 
-	return nil
+// onlineCounter queries the online counter as CSV.
+func (s *Scraper) onlineCounter() ([]fritz.OnlineCounter, error) {
+	out, err := s.query("internet/inetstat_counter.lua", "csv=", "GET", nil)
+	if err != nil {
+		return nil, err
+	}
+	level.Debug(s.logger).Log("inetstat_counter", out)
+	counters, err := fritz.DecodeOnlineCounter(out)
+	if err != nil {
+		return nil, fmt.Errorf("decoding online counter: %w", err)
+	}
+	return counters, nil
 }
 
 func (s *Scraper) loadServices() error {
-	device := regexp.MustCompile("http.*://(.*)/.*").FindAllStringSubmatch(s.cfg.FritzBoxURL, 1)
-	root, err := fritzbox_upnp.LoadServices(device[0][1], 49000)
+	root, err := s.session.Services()
 	if err != nil {
 		return err
 	}
-	s.upnpServicesRoot = root
 	for name, se := range root.Services {
 		for a, _ := range se.Actions {
 
@@ -332,78 +417,117 @@ func (s *Scraper) loadServices() error {
 	return err
 }
 
-func (s *Scraper) getLinkInfo() (bytesSent, bytesReceived float64, wanAccessType, linkStatus string, upstream, downstream float64) {
-	if s.upnpServicesRoot == nil {
-		level.Warn(s.logger).Log("Services not loaded")
-		return
-	}
-	service, _ := s.upnpServicesRoot.Services["urn:schemas-upnp-org:service:WANCommonInterfaceConfig:1"]
+// wanLinkInfo is the WAN link state reported via TR-064.
+type wanLinkInfo struct {
+	bytesSent     float64
+	bytesReceived float64
+	// counterWrap is the value at which the byte counters wrap around, 0
+	// if the box reports 64 bit counters
+	counterWrap   float64
+	wanAccessType string
+	linkStatus    string
+	upstream      float64
+	downstream    float64
+}
 
-	action, _ := service.Actions["GetAddonInfos"]
-	res, err := action.Call()
+func (s *Scraper) getLinkInfo() (info wanLinkInfo, err error) {
+	service := "urn:schemas-upnp-org:service:WANCommonInterfaceConfig:1"
+
+	res, err := s.session.Call(service, "GetAddonInfos")
 	if err != nil {
-		level.Warn(s.logger).Log("Failed to execute action call", "action", action.Name)
-	}
-	resbytesSent, _ := res["TotalBytesSent"]
-	switch v := resbytesSent.(type) {
-	case uint64:
-		bytesSent = float64(v)
+		err = fmt.Errorf("GetAddonInfos: %w", err)
+		return
 	}
-	resBytesReceived, _ := res["TotalBytesReceived"]
-	switch v := resBytesReceived.(type) {
-	case uint64:
-		bytesReceived = float64(v)
+	// newer firmware adds 64 bit counters, the standard ones wrap at 4 GiB
+	sent64, ok64 := res["X_AVM_DE_TotalBytesSent64"].(string)
+	received64, _ := res["X_AVM_DE_TotalBytesReceived64"].(string)
+	if ok64 {
+		info.bytesSent, err = strconv.ParseFloat(sent64, 64)
+		if err == nil {
+			info.bytesReceived, err = strconv.ParseFloat(received64, 64)
+		}
+		if err != nil {
+			return info, fmt.Errorf("parsing 64 bit byte counters: %w", err)
+		}
+	} else {
+		info.counterWrap = 1 << 32
+		resbytesSent, _ := res["TotalBytesSent"]
+		switch v := resbytesSent.(type) {
+		case uint64:
+			info.bytesSent = float64(v)
+		}
+		resBytesReceived, _ := res["TotalBytesReceived"]
+		switch v := resBytesReceived.(type) {
+		case uint64:
+			info.bytesReceived = float64(v)
+		}
 	}
 
-	action, _ = service.Actions["GetCommonLinkProperties"]
-	res, err = action.Call()
+	err = s.linkProperties(&info)
+	return
+}
+
+// linkProperties queries access type, physical state and maximum rates of
+// the WAN link into info.
+func (s *Scraper) linkProperties(info *wanLinkInfo) error {
+	res, err := s.session.Call("urn:schemas-upnp-org:service:WANCommonInterfaceConfig:1", "GetCommonLinkProperties")
 	if err != nil {
-		level.Warn(s.logger).Log("Failed to execute action call", action.Name)
+		return fmt.Errorf("GetCommonLinkProperties: %w", err)
 	}
 	resWanAccessType, _ := res["WANAccessType"]
 	switch v := resWanAccessType.(type) {
 	case string:
-		wanAccessType = v
+		info.wanAccessType = v
 	}
 	resLinkStatus, _ := res["PhysicalLinkStatus"]
 	switch v := resLinkStatus.(type) {
 	case string:
-		linkStatus = v
+		info.linkStatus = v
 	}
 	resUpstream, _ := res["Layer1UpstreamMaxBitRate"]
 	switch tval := resUpstream.(type) {
 	case uint64:
-		upstream = float64(tval)
+		info.upstream = float64(tval)
 
 	}
 	resDownstream, _ := res["Layer1DownstreamMaxBitRate"]
 	switch tval := resDownstream.(type) {
 	case uint64:
-		downstream = float64(tval)
+		info.downstream = float64(tval)
 
 	}
-
-	return
+	return nil
 }
 
-func (s *Scraper) getConnectionInfo() (extIPV6, extIPV4, connectionStatus, connectionError string, uptime float64) {
-	if s.upnpServicesRoot == nil {
-		level.Warn(s.logger).Log("Services not loaded")
+func (s *Scraper) getConnectionInfo() (extIPV6, extIPV4, connectionStatus, connectionError string, uptime float64, err error) {
+	service := "urn:schemas-upnp-org:service:WANIPConnection:1"
+
+	res, err := s.session.Call(service, "GetStatusInfo")
+	if err != nil {
+		err = fmt.Errorf("GetStatusInfo: %w", err)
 		return
 	}
-	service, ok := s.upnpServicesRoot.Services["urn:schemas-upnp-org:service:WANIPConnection:1"]
-	if !ok {
-		level.Warn(s.logger).Log("Service not available")
-		return
+	resConnStatus, _ := res["ConnectionStatus"]
+	switch v := resConnStatus.(type) {
+	case string:
+		connectionStatus = string(v)
 	}
-	action, ok := service.Actions["X_AVM_DE_GetExternalIPv6Address"]
-	if !ok {
-		level.Warn(s.logger).Log("Action X_AVM_DE_GetExternalIPv6Address not available")
-		return
+	resConnError, _ := res["LastConnectionError"]
+	switch v := resConnError.(type) {
+	case string:
+		connectionError = string(v)
 	}
-	res, err := action.Call()
-	if err != nil {
-		level.Warn(s.logger).Log("Failed to execute action call", action.Name)
+	resUptime, _ := res["Uptime"]
+	switch tval := resUptime.(type) {
+	case uint64:
+		uptime = float64(tval)
+
+	}
+
+	// the addresses are optional, e.g. there is no IPv6 on many lines
+	res, ipErr := s.session.Call(service, "X_AVM_DE_GetExternalIPv6Address")
+	if ipErr != nil {
+		level.Debug(s.logger).Log("Failed to execute action call", "action", "X_AVM_DE_GetExternalIPv6Address", "err", ipErr)
 	}
 	resExtIpV6, _ := res["ExternalIPv6Address"]
 	switch v := resExtIpV6.(type) {
@@ -411,10 +535,11 @@ func (s *Scraper) getConnectionInfo() (extIPV6, extIPV4, connectionStatus, conne
 		extIPV6 = string(v)
 	}
 
-	action, _ = service.Actions["GetExternalIPAddress"]
-	res, err = action.Call()
-	if err != nil {
-		level.Warn(s.logger).Log("Failed to execute action call", action.Name)
+	res, ipErr = s.session.Call(service, "GetExternalIPAddress")
+	if errors.Is(ipErr, fritz.ErrNotSupported) {
+		level.Debug(s.logger).Log("Failed to execute action call", "action", "GetExternalIPAddress", "err", ipErr)
+	} else if ipErr != nil {
+		level.Warn(s.logger).Log("Failed to execute action call", "action", "GetExternalIPAddress", "err", ipErr)
 	}
 	resExtIpV4, _ := res["ExternalIPAddress"]
 	switch v := resExtIpV4.(type) {
@@ -422,29 +547,304 @@ func (s *Scraper) getConnectionInfo() (extIPV6, extIPV4, connectionStatus, conne
 		extIPV4 = string(v)
 	}
 
-	action, _ = service.Actions["GetStatusInfo"]
-	res, err = action.Call()
+	return
+}
+
+// dslInfo queries the DSL line state and error counters via TR-064.
+func (s *Scraper) dslInfo() (*fritz.DSLInfo, error) {
+	info, err := s.session.CallAction(fritz.DSLService, "GetInfo", nil)
 	if err != nil {
-		level.Warn(s.logger).Log("Failed to execute action call", action.Name)
+		return nil, err
 	}
-	resConnStatus, _ := res["ConnectionStatus"]
-	switch v := resConnStatus.(type) {
-	case string:
-		connectionStatus = string(v)
+	stats, err := s.session.CallAction(fritz.DSLService, "GetStatisticsTotal", nil)
+	if err != nil {
+		return nil, err
 	}
-	resConnError, _ := res["LastConnectionError"]
-	switch v := resConnError.(type) {
-	case string:
-		connectionError = string(v)
+	return fritz.DecodeDSLInfo(info, stats)
+}
+
+// deviceInfo queries model, firmware, uptime and update state of the box via TR-064.
+func (s *Scraper) deviceInfo() (*fritz.DeviceInfo, error) {
+	info, err := s.deviceInfoArgs()
+	if err != nil {
+		return nil, err
 	}
-	resUptime, _ := res["Uptime"]
-	switch tval := resUptime.(type) {
-	case uint64:
-		uptime = float64(tval)
+	ui, err := s.session.CallAction(fritz.UserInterfaceService, "GetInfo", nil)
+	if err != nil {
+		return nil, err
+	}
+	return fritz.DecodeDeviceInfo(info, ui)
+}
 
+// uptime returns the seconds since the last reboot of the box.
+func (s *Scraper) uptime() (float64, error) {
+	info, err := s.deviceInfoArgs()
+	if err != nil {
+		return 0, err
 	}
+	return fritz.DecodeUptime(info)
+}
 
-	return
+// deviceInfoArgs returns the output arguments of GetInfo of the DeviceInfo
+// service, answers younger than deviceInfoMaxAge are reused.
+func (s *Scraper) deviceInfoArgs() (map[string]string, error) {
+	s.mu.Lock()
+	if s.lastDeviceInfo != nil && time.Since(s.lastDeviceInfoFetched) < deviceInfoMaxAge {
+		defer s.mu.Unlock()
+		return s.lastDeviceInfo, nil
+	}
+	s.mu.Unlock()
+
+	info, err := s.session.CallAction(fritz.DeviceInfoService, "GetInfo", nil)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastDeviceInfo = info
+	s.lastDeviceInfoFetched = time.Now()
+	return info, nil
+}
+
+// mobileInfo queries the LTE/5G modem via TR-064. Only GetInfo is
+// required, the other actions add details on newer firmware.
+func (s *Scraper) mobileInfo() (*fritz.MobileInfo, error) {
+	info, err := s.session.CallAction(fritz.MobileService, "GetInfo", nil)
+	if err != nil {
+		return nil, err
+	}
+	optional := func(action string) map[string]string {
+		res, err := s.session.CallAction(fritz.MobileService, action, nil)
+		if err != nil {
+			level.Debug(s.logger).Log("msg", "Failed to execute action call", "action", action, "err", err)
+		}
+		return res
+	}
+	return fritz.DecodeMobileInfo(info, optional("GetInfoEx"), optional("GetAccessTechnology")), nil
+}
+
+// wlanRadios queries the WLANConfiguration services the box offers, GetInfo
+// is required, the other actions add details.
+func (s *Scraper) wlanRadios() ([]*fritz.WLANRadio, error) {
+	var radios []*fritz.WLANRadio
+	for n := 1; n <= 4; n++ {
+		service := fritz.WLANService(n)
+		args, err := s.session.CallAction(service, "GetInfo", nil)
+		if errors.Is(err, fritz.ErrNotSupported) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, action := range []string{"GetTotalAssociations", "GetChannelInfo", "X_AVM-DE_GetWLANExtInfo"} {
+			res, err := s.session.CallAction(service, action, nil)
+			if err != nil {
+				level.Debug(s.logger).Log("msg", "Failed to execute action call", "service", service, "action", action, "err", err)
+				continue
+			}
+			for name, value := range res {
+				args[name] = value
+			}
+		}
+		radios = append(radios, fritz.DecodeWLANRadio(n, args))
+	}
+	if len(radios) == 0 {
+		return nil, fmt.Errorf("WLANConfiguration: %w", fritz.ErrNotSupported)
+	}
+	return radios, nil
+}
+
+// wlanChannels queries the neighbor networks and channel usage.
+func (s *Scraper) wlanChannels() ([]fritz.WLANNeighbor, []fritz.WLANChannelUsage, error) {
+	dData := url.Values{}
+	dData.Set("xhr", "1")
+	dData.Set("xhrId", "all")
+	dData.Set("lang", "de")
+	dData.Set("page", "chan")
+	dData.Set("no_siderenew", "")
+
+	chanData, err := s.query("data.lua", "", "POST", dData)
+	if err != nil {
+		return nil, nil, err
+	}
+	level.Debug(s.logger).Log("chan", chanData)
+	neighbors, usage, err := fritz.DecodeWLANChannels(chanData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding channel page: %w", err)
+	}
+	return neighbors, usage, nil
+}
+
+// meshGraph fetches the mesh list, whose path is handed out via TR-064.
+func (s *Scraper) meshGraph() (*fritz.MeshGraph, error) {
+	res, err := s.session.CallAction(fritz.HostsService, "X_AVM-DE_GetMeshListPath", nil)
+	if err != nil {
+		return nil, err
+	}
+	path, ok := res["NewX_AVM-DE_MeshListPath"]
+	if !ok {
+		return nil, fmt.Errorf("mesh list path: %w", fritz.ErrInvalidResponse)
+	}
+	data, err := s.session.GetTR064(path)
+	if err != nil {
+		return nil, err
+	}
+	g, err := fritz.DecodeMeshList(data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding mesh list: %w", err)
+	}
+	return g, nil
+}
+
+// voipNumbers queries the SIP accounts and their registration state.
+// GetExistingVoIPNumbers tells how many accounts there are, unused
+// indexes in between answer with a SOAP error.
+func (s *Scraper) voipNumbers() ([]fritz.VoIPNumber, error) {
+	res, err := s.session.CallAction(fritz.VoIPService, "GetExistingVoIPNumbers", nil)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := strconv.Atoi(res["NewExistingVoIPNumbers"])
+	if err != nil {
+		return nil, fmt.Errorf("number of VoIP accounts %q: %w", res["NewExistingVoIPNumbers"], fritz.ErrInvalidResponse)
+	}
+	var numbers []fritz.VoIPNumber
+	for i := 0; i < fritz.MaxVoIPAccounts && len(numbers) < existing; i++ {
+		args := map[string]string{"NewVoIPAccountIndex": strconv.Itoa(i)}
+		account, err := s.session.CallAction(fritz.VoIPService, "X_AVM-DE_GetVoIPAccount", args)
+		var soapErr *fritz.SOAPError
+		if errors.As(err, &soapErr) && !errors.Is(err, fritz.ErrNotSupported) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		status, err := s.session.CallAction(fritz.VoIPService, "X_AVM-DE_GetVoIPStatus", args)
+		if err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, fritz.DecodeVoIPNumber(i, account, status))
+	}
+	return numbers, nil
+}
+
+// callList fetches the call list from the URL returned by GetCallList.
+func (s *Scraper) callList() ([]fritz.Call, error) {
+	res, err := s.session.CallAction(fritz.OnTelService, "GetCallList", nil)
+	if err != nil {
+		return nil, err
+	}
+	listURL, err := url.Parse(res["NewCallListURL"])
+	if err != nil || listURL.Path == "" {
+		return nil, fmt.Errorf("call list URL %q: %w", res["NewCallListURL"], fritz.ErrInvalidResponse)
+	}
+	data, err := s.session.GetTR064(listURL.RequestURI())
+	if err != nil {
+		return nil, err
+	}
+	calls, err := fritz.DecodeCallList(data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding call list: %w", err)
+	}
+	return calls, nil
+}
+
+// tams queries the answering machines and counts the messages of each.
+func (s *Scraper) tams() ([]fritz.TAM, error) {
+	res, err := s.session.CallAction(fritz.TAMService, "GetList", nil)
+	if err != nil {
+		return nil, err
+	}
+	tams, err := fritz.DecodeTAMList(res["NewTAMList"])
+	if err != nil {
+		return nil, err
+	}
+	for i, tam := range tams {
+		res, err := s.session.CallAction(fritz.TAMService, "GetMessageList", map[string]string{"NewIndex": tam.Index})
+		if err != nil {
+			return nil, err
+		}
+		listURL, err := url.Parse(res["NewURL"])
+		if err != nil || listURL.Path == "" {
+			return nil, fmt.Errorf("message list URL %q: %w", res["NewURL"], fritz.ErrInvalidResponse)
+		}
+		data, err := s.session.GetTR064(listURL.RequestURI())
+		if err != nil {
+			return nil, err
+		}
+		tams[i].Messages, tams[i].NewMessages, err = fritz.CountTAMMessages(data)
+		if err != nil {
+			return nil, fmt.Errorf("decoding message list of TAM %s: %w", tam.Index, err)
+		}
+	}
+	return tams, nil
+}
+
+// dectHandsets queries the DECT handsets registered at the box via TR-064,
+// firmware and battery level are added from query.lua if the box knows them.
+func (s *Scraper) dectHandsets() ([]fritz.DECTHandset, error) {
+	res, err := s.session.CallAction(fritz.DECTService, "GetNumberOfDectEntries", nil)
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(res["NewNumberOfEntries"])
+	if err != nil {
+		return nil, fmt.Errorf("number of DECT entries %q: %w", res["NewNumberOfEntries"], fritz.ErrInvalidResponse)
+	}
+	entries := make([]map[string]string, 0, n)
+	for i := 0; i < n; i++ {
+		args, err := s.session.CallAction(fritz.DECTService, "GetGenericDectEntry", map[string]string{"NewIndex": strconv.Itoa(i)})
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, args)
+	}
+	handsets := fritz.DecodeDECTHandsets(entries)
+
+	// the details are optional, the handsets are exported without them
+	body, err := s.session.Query("query.lua", "handsets=dect:settings/Handset/list(ID,FWVersion,BatteryCharge)", "GET", nil)
+	if err != nil {
+		level.Warn(s.logger).Log("msg", "Failed to query DECT handset details", "err", err)
+		return handsets, nil
+	}
+	level.Debug(s.logger).Log("handsets", body)
+	details, err := fritz.DecodeDECTHandsetDetails(body)
+	if errors.Is(err, fritz.ErrNotSupported) {
+		level.Debug(s.logger).Log("msg", "DECT handset details not supported by box", "err", err)
+		return handsets, nil
+	}
+	if err != nil {
+		level.Warn(s.logger).Log("msg", "Failed to decode DECT handset details", "err", err)
+		return handsets, nil
+	}
+	fritz.AddDECTHandsetDetails(handsets, details)
+	return handsets, nil
+}
+
+// docsisInfo queries the cable channel list.
+func (s *Scraper) docsisInfo() (*fritz.DocsisInfo, error) {
+	dData := url.Values{}
+	dData.Set("xhr", "1")
+	dData.Set("xhrId", "all")
+	dData.Set("lang", "de")
+	dData.Set("page", "docInfo")
+	dData.Set("no_siderenew", "")
+
+	docInfo, err := s.query("data.lua", "", "POST", dData)
+	if err != nil {
+		return nil, err
+	}
+	level.Debug(s.logger).Log("docInfo", docInfo)
+	return fritz.DecodeDocsisInfo(docInfo)
+}
+
+func (s *Scraper) smartHomeDevices() ([]fritz.SmartHomeDevice, error) {
+	deviceList, err := s.query("webservices/homeautoswitch.lua", "switchcmd=getdevicelistinfos", "GET", nil)
+	if err != nil {
+		return nil, err
+	}
+	level.Debug(s.logger).Log("deviceList", deviceList)
+	return fritz.DecodeSmartHomeDevices(deviceList)
 }
 
 func (s *Scraper) deviceSpecificData(UID string) (fritz.NetDevice, error) {
@@ -472,33 +872,55 @@ func (s *Scraper) deviceSpecificData(UID string) (fritz.NetDevice, error) {
 	return fd, err
 }
 
-//
-//func (s *Scraper) generalInfo() {
-//	dData := url.Values{}
-//	dData.Set("xhr", "1")
-//	dData.Set("xhrId", "all")
-//	dData.Set("lang", "de")
-//	dData.Set("page", "overview")
-//	//dData.Set("initialRefreshParamsSaved", "true")
-//	dData.Set("no_siderenew", "")
-//
-//	overviewData, err := s.query("data.lua", "", "POST", dData)
-//	if err != nil {
-//		level.Warn(s.logger).Log("Failure querying overview data", "error", err)
-//	} else {
-//		level.Debug(s.logger).Log("data", overviewData)
-//
-//		overview, err := fritz.DecodeOverViewData(overviewData)
-//		if err != nil {
-//			level.Warn(s.logger).Log("Failure parsing overview data", "error", err)
-//		} else {
-//			level.Info(s.logger).Log("FritzBox", overview.FritzOS.ProductName, "FritzOS", overview.FritzOS.Version)
-//			level.Info(s.logger).Log("uptime", overview.Internet.Uptime, "internet", overview.Internet.Txt)
-//		}
-//	}
-//}
-
-func (s *Scraper) queryLogs() {
+func (s *Scraper) overview() (fritz.Overview, error) {
+	dData := url.Values{}
+	dData.Set("xhr", "1")
+	dData.Set("xhrId", "all")
+	dData.Set("lang", "de")
+	dData.Set("page", "overview")
+	dData.Set("no_siderenew", "")
+
+	overviewData, err := s.query("data.lua", "", "POST", dData)
+	if err != nil {
+		return fritz.Overview{}, err
+	}
+	level.Debug(s.logger).Log("overview", overviewData)
+	return fritz.DecodeOverViewData(overviewData)
+}
+
+func (s *Scraper) ecoStat() (*fritz.EcoStat, error) {
+	dData := url.Values{}
+	dData.Set("xhr", "1")
+	dData.Set("xhrId", "all")
+	dData.Set("lang", "de")
+	dData.Set("page", "ecoStat")
+	dData.Set("no_siderenew", "")
+
+	ecoStat, err := s.query("data.lua", "", "POST", dData)
+	if err != nil {
+		return nil, err
+	}
+	level.Debug(s.logger).Log("ecoStat", ecoStat)
+	return fritz.DecodeEcoStat(ecoStat)
+}
+
+func (s *Scraper) energyUsage() ([]fritz.EnergyUsage, error) {
+	dData := url.Values{}
+	dData.Set("xhr", "1")
+	dData.Set("xhrId", "all")
+	dData.Set("lang", "de")
+	dData.Set("page", "energy")
+	dData.Set("no_siderenew", "")
+
+	energy, err := s.query("data.lua", "", "POST", dData)
+	if err != nil {
+		return nil, err
+	}
+	level.Debug(s.logger).Log("energy", energy)
+	return fritz.DecodeEnergyUsage(energy)
+}
+
+func (s *Scraper) queryLogs() error {
 	logData := url.Values{}
 	logData.Set("page", "log")
 	logData.Set("xhr", "1")
@@ -509,24 +931,29 @@ func (s *Scraper) queryLogs() {
 
 	logs, err := s.query("data.lua", "", "POST", logData)
 	if err != nil {
-		level.Warn(s.logger).Log("Error", err)
+		return err
 	}
 	level.Debug(s.logger).Log("logs", logs)
 	loglines := &fritz.Logs{}
 	err = loglines.Decode(logs)
 	if err != nil {
-		level.Warn(s.logger).Log("Error", err)
-	} else {
+		return fmt.Errorf("decoding logs: %w", err)
+	} else if len(loglines.Data.LogLines) > 0 {
+		newestLogTime := loglines.Data.LogLines[0].Timestamp
+		if s.config().FilterOwnLogin {
+			loglines.DropLogins(s.target.Username, s.session.LocalIP())
+		}
 		// process log lines -> write to file on disk?
-		jsonLines, _ := loglines.EncodeAfter(*lastLogTime)
-		err = s.logPusher.Push(jsonLines)
+		jsonLines, _ := loglines.EncodeAfter(s.lastLogTime)
+		logPusher := s.pusher()
+		err = logPusher.Push(jsonLines)
 		if err != nil {
 			level.Warn(s.logger).Log("message", "cannot send logs", "error", err)
 		}
-		f, err := os.OpenFile(s.cfg.LogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		f, err := os.OpenFile(s.target.LogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 
 		if err != nil {
-			level.Warn(s.logger).Log("Failed to open fritz-log-path", s.cfg.LogPath, "err", err)
+			level.Warn(s.logger).Log("Failed to open fritz-log-path", s.target.LogPath, "err", err)
 		} else {
 			defer f.Close()
 			for _, line := range jsonLines {
@@ -540,46 +967,18 @@ func (s *Scraper) queryLogs() {
 				level.Warn(s.logger).Log("message", "Failed to sync buffer with file", "err", err)
 			}
 		}
-		*lastLogTime = loglines.Data.LogLines[0].Timestamp
+		s.lastLogTime = newestLogTime
 	}
-
+	return nil
 }
 
 func (s *Scraper) query(path string, options string, method string, urlData url.Values) (string, error) {
-	if options == "" {
-		options = "0=0"
-	}
-	var body io.Reader
-	var uri string
-	if urlData != nil {
-		urlData.Set("sid", *loginSid)
-		body = strings.NewReader(urlData.Encode())
-		uri = s.cfg.FritzBoxURL + "/" + path
-	} else {
-		uri = s.cfg.FritzBoxURL + "/" + path + "?sid=" + *loginSid + "&" + options
-
-	}
-
-	client := http.Client{
-		Timeout: time.Duration(10 * time.Second),
-	}
-	request, err := http.NewRequest(method, uri, body)
-	if err != nil {
-		level.Warn(s.logger).Log("Invalid request", err)
-	}
-	request.Header.Set("Content-type", "application/x-www-form-urlencoded")
-	resp, err := client.Do(request)
-
+	body, err := s.session.Query(path, options, method, urlData)
 	if err != nil {
-		level.Warn(s.logger).Log("Error logging in", err)
+		level.Warn(s.logger).Log("Error querying", path, "err", err)
 		return "", err
-	} else {
-		defer resp.Body.Close()
-		body, _ := ioutil.ReadAll(resp.Body)
-		//level.Debug(s.logger).Log("body", body)
-		return string(body), nil
 	}
-
+	return body, nil
 }
 
 func getOtherBand(band string) string {
@@ -0,0 +1,93 @@
+package scraper
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("smarthome", false, func(s *Scraper) Collector { return newSmartHomeCollector(s) })
+}
+
+// smartHomeCollector exports the FRITZ!DECT plugs, thermostats and sensors
+// of the AHA device list.
+type smartHomeCollector struct {
+	s                  *Scraper
+	info               *prometheus.Desc
+	present            *prometheus.Desc
+	switchOn           *prometheus.Desc
+	power              *prometheus.Desc
+	energy             *prometheus.Desc
+	voltage            *prometheus.Desc
+	temperature        *prometheus.Desc
+	currentTemperature *prometheus.Desc
+	targetTemperature  *prometheus.Desc
+	battery            *prometheus.Desc
+	batteryLow         *prometheus.Desc
+	windowOpen         *prometheus.Desc
+}
+
+func newSmartHomeCollector(s *Scraper) *smartHomeCollector {
+	labels := []string{"ain", "name"}
+	return &smartHomeCollector{
+		s:                  s,
+		info:               s.newDesc("fritzbox_smarthome_device_info", "Gauge showing product and firmware of the smart home device", append(labels, "product", "firmware")...),
+		present:            s.newDesc("fritzbox_smarthome_present", "Gauge showing whether the smart home device is connected to the box", labels...),
+		switchOn:           s.newDesc("fritzbox_smarthome_switch_on", "Gauge showing whether the outlet is switched on", labels...),
+		power:              s.newDesc("fritzbox_smarthome_power_watts", "Gauge showing the power drawn through the outlet", labels...),
+		energy:             s.newDesc("fritzbox_smarthome_energy_watt_hours_total", "Counter of the energy drawn through the outlet", labels...),
+		voltage:            s.newDesc("fritzbox_smarthome_voltage_volts", "Gauge showing the voltage at the outlet", labels...),
+		temperature:        s.newDesc("fritzbox_smarthome_temperature_celsius", "Gauge showing the temperature measured by the device", labels...),
+		currentTemperature: s.newDesc("fritzbox_smarthome_thermostat_current_celsius", "Gauge showing the current temperature of the thermostat", labels...),
+		targetTemperature:  s.newDesc("fritzbox_smarthome_thermostat_target_celsius", "Gauge showing the target temperature of the thermostat", labels...),
+		battery:            s.newDesc("fritzbox_smarthome_battery_percent", "Gauge showing the battery level of the device", labels...),
+		batteryLow:         s.newDesc("fritzbox_smarthome_battery_low", "Gauge showing whether the battery of the device is low", labels...),
+		windowOpen:         s.newDesc("fritzbox_smarthome_window_open", "Gauge showing whether the thermostat detected an open window", labels...),
+	}
+}
+
+func (c *smartHomeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.info
+	ch <- c.present
+	ch <- c.switchOn
+	ch <- c.power
+	ch <- c.energy
+	ch <- c.voltage
+	ch <- c.temperature
+	ch <- c.currentTemperature
+	ch <- c.targetTemperature
+	ch <- c.battery
+	ch <- c.batteryLow
+	ch <- c.windowOpen
+}
+
+func (c *smartHomeCollector) Update(ch chan<- prometheus.Metric) error {
+	devices, err := c.s.smartHomeDevices()
+	if err != nil {
+		return err
+	}
+	for _, d := range devices {
+		labels := []string{d.AIN, d.Name}
+		send := func(desc *prometheus.Desc, valueType prometheus.ValueType, value *float64) {
+			if value != nil {
+				ch <- prometheus.MustNewConstMetric(desc, valueType, *value, labels...)
+			}
+		}
+		present := 0.0
+		if d.Present {
+			present = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, append(labels, d.Product, d.Firmware)...)
+		ch <- prometheus.MustNewConstMetric(c.present, prometheus.GaugeValue, present, labels...)
+		send(c.switchOn, prometheus.GaugeValue, d.SwitchOn)
+		send(c.power, prometheus.GaugeValue, d.Power)
+		send(c.energy, prometheus.CounterValue, d.Energy)
+		send(c.voltage, prometheus.GaugeValue, d.Voltage)
+		send(c.temperature, prometheus.GaugeValue, d.Temperature)
+		send(c.currentTemperature, prometheus.GaugeValue, d.CurrentTemperature)
+		send(c.targetTemperature, prometheus.GaugeValue, d.TargetTemperature)
+		send(c.battery, prometheus.GaugeValue, d.Battery)
+		send(c.batteryLow, prometheus.GaugeValue, d.BatteryLow)
+		send(c.windowOpen, prometheus.GaugeValue, d.WindowOpen)
+	}
+	return nil
+}
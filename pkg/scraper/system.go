@@ -0,0 +1,88 @@
+package scraper
+
+import (
+	"errors"
+
+	"github.com/wbwue/FritzExporter/pkg/fritz"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+func init() {
+	registerCollector("system", true, func(s *Scraper) Collector { return newSystemCollector(s) })
+}
+
+// systemCollector exports model and firmware of the box and the load,
+// memory and power figures of its energy monitor.
+type systemCollector struct {
+	s              *Scraper
+	info           *prometheus.Desc
+	cpuLoad        *prometheus.Desc
+	cpuTemperature *prometheus.Desc
+	ramUsage       *prometheus.Desc
+	energy         *prometheus.Desc
+}
+
+func newSystemCollector(s *Scraper) *systemCollector {
+	return &systemCollector{
+		s:              s,
+		info:           s.newDesc("fritzbox_info", "Gauge showing model and firmware version of the box", "model", "firmware"),
+		cpuLoad:        s.newDesc("fritzbox_system_cpu_load_percent", "Gauge showing the current CPU load of the box"),
+		cpuTemperature: s.newDesc("fritzbox_system_cpu_temperature_celsius", "Gauge showing the current CPU temperature of the box"),
+		ramUsage:       s.newDesc("fritzbox_system_ram_usage_percent", "Gauge showing the share of the RAM used by the firmware (fixed), at runtime (dynamic) and not in use (free)", "type"),
+		energy:         s.newDesc("fritzbox_system_energy_consumption_percent", "Gauge showing the current power consumption of a subsystem relative to its maximum", "subsystem"),
+	}
+}
+
+func (c *systemCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.info
+	ch <- c.cpuLoad
+	ch <- c.cpuTemperature
+	ch <- c.ramUsage
+	ch <- c.energy
+}
+
+func (c *systemCollector) Update(ch chan<- prometheus.Metric) error {
+	overview, err := c.s.overview()
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, overview.FritzOS.ProductName, overview.FritzOS.Version)
+
+	// the energy monitor pages need the settings permission and are missing
+	// on repeaters, the box info is exported without them
+	send := func(desc *prometheus.Desc, value *float64, labels ...string) {
+		if value != nil {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, *value, labels...)
+		}
+	}
+	ecoStat, err := c.s.ecoStat()
+	if err != nil {
+		c.optionalFailed("ecoStat", err)
+	} else {
+		send(c.cpuLoad, ecoStat.CPULoad)
+		send(c.cpuTemperature, ecoStat.CPUTemperature)
+		send(c.ramUsage, ecoStat.RAMFixed, "fixed")
+		send(c.ramUsage, ecoStat.RAMDynamic, "dynamic")
+		send(c.ramUsage, ecoStat.RAMFree, "free")
+	}
+	energy, err := c.s.energyUsage()
+	if err != nil {
+		c.optionalFailed("energy", err)
+	} else {
+		for _, v := range energy {
+			ch <- prometheus.MustNewConstMetric(c.energy, prometheus.GaugeValue, v.Percent, v.Subsystem)
+		}
+	}
+	return nil
+}
+
+func (c *systemCollector) optionalFailed(page string, err error) {
+	if errors.Is(err, fritz.ErrNotSupported) {
+		level.Debug(c.s.logger).Log("msg", "page not supported by box", "page", page, "err", err)
+		return
+	}
+	level.Warn(c.s.logger).Log("msg", "Failed to query energy monitor", "page", page, "err", err)
+}
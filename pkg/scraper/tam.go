@@ -0,0 +1,101 @@
+package scraper
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("tam", false, func(s *Scraper) Collector { return newTAMCollector(s) })
+	registerCollector("dect", false, func(s *Scraper) Collector { return newDECTCollector(s) })
+}
+
+// tamCollector exports the answering machines and their messages.
+type tamCollector struct {
+	s           *Scraper
+	enabled     *prometheus.Desc
+	messages    *prometheus.Desc
+	newMessages *prometheus.Desc
+}
+
+func newTAMCollector(s *Scraper) *tamCollector {
+	labels := []string{"tam", "name"}
+	return &tamCollector{
+		s:           s,
+		enabled:     s.newDesc("fritzbox_tam_enabled", "Gauge showing whether the answering machine is switched on", labels...),
+		messages:    s.newDesc("fritzbox_tam_messages", "Gauge showing the number of messages on the answering machine", labels...),
+		newMessages: s.newDesc("fritzbox_tam_new_messages", "Gauge showing the number of unheard messages on the answering machine", labels...),
+	}
+}
+
+func (c *tamCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.enabled
+	ch <- c.messages
+	ch <- c.newMessages
+}
+
+func (c *tamCollector) Update(ch chan<- prometheus.Metric) error {
+	tams, err := c.s.tams()
+	if err != nil {
+		return err
+	}
+	for _, t := range tams {
+		enabled := 0.0
+		if t.Enabled {
+			enabled = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.enabled, prometheus.GaugeValue, enabled, t.Index, t.Name)
+		ch <- prometheus.MustNewConstMetric(c.messages, prometheus.GaugeValue, float64(t.Messages), t.Index, t.Name)
+		ch <- prometheus.MustNewConstMetric(c.newMessages, prometheus.GaugeValue, float64(t.NewMessages), t.Index, t.Name)
+	}
+	return nil
+}
+
+// dectCollector exports the DECT handsets registered at the box.
+type dectCollector struct {
+	s               *Scraper
+	info            *prometheus.Desc
+	registered      *prometheus.Desc
+	updateAvailable *prometheus.Desc
+	battery         *prometheus.Desc
+}
+
+func newDECTCollector(s *Scraper) *dectCollector {
+	labels := []string{"id", "name"}
+	return &dectCollector{
+		s:               s,
+		info:            s.newDesc("fritzbox_dect_handset_info", "Gauge showing model and firmware of the DECT handset", append(labels, "model", "firmware")...),
+		registered:      s.newDesc("fritzbox_dect_handset_registered", "Gauge showing whether the DECT handset is registered and active", labels...),
+		updateAvailable: s.newDesc("fritzbox_dect_handset_update_available", "Gauge showing whether a firmware update is available for the DECT handset", labels...),
+		battery:         s.newDesc("fritzbox_dect_handset_battery_percent", "Gauge showing the battery level of the DECT handset", labels...),
+	}
+}
+
+func (c *dectCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.info
+	ch <- c.registered
+	ch <- c.updateAvailable
+	ch <- c.battery
+}
+
+func (c *dectCollector) Update(ch chan<- prometheus.Metric) error {
+	handsets, err := c.s.dectHandsets()
+	if err != nil {
+		return err
+	}
+	for _, h := range handsets {
+		registered, updateAvailable := 0.0, 0.0
+		if h.Registered {
+			registered = 1
+		}
+		if h.UpdateAvailable {
+			updateAvailable = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, h.ID, h.Name, h.Model, h.Firmware)
+		ch <- prometheus.MustNewConstMetric(c.registered, prometheus.GaugeValue, registered, h.ID, h.Name)
+		ch <- prometheus.MustNewConstMetric(c.updateAvailable, prometheus.GaugeValue, updateAvailable, h.ID, h.Name)
+		if h.Battery != nil {
+			ch <- prometheus.MustNewConstMetric(c.battery, prometheus.GaugeValue, *h.Battery, h.ID, h.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package scraper
+
+import (
+	"strconv"
+
+	"github.com/wbwue/FritzExporter/pkg/fritz"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("telephony", false, func(s *Scraper) Collector { return newTelephonyCollector(s) })
+}
+
+// callTypes are the finished calls counted by the telephony collector.
+var callTypes = []string{"incoming", "missed", "outgoing", "blocked"}
+
+// callDurationBuckets in seconds, the box reports durations in minutes.
+var callDurationBuckets = []float64{60, 300, 600, 1200, 1800, 3600, 7200}
+
+// telephonyCollector exports the registration state of the SIP accounts
+// and counts the calls that show up in the call list. The call list only
+// holds the latest calls, so the counters start at 0 with the exporter and
+// add the calls that appear between two updates. The collector is kept on
+// reloads, so they don't reset the counters.
+type telephonyCollector struct {
+	s            *Scraper
+	registered   *prometheus.Desc
+	calls        *prometheus.CounterVec
+	callDuration *prometheus.HistogramVec
+
+	seen map[int]bool
+}
+
+func newTelephonyCollector(s *Scraper) *telephonyCollector {
+	c := &telephonyCollector{
+		s:          s,
+		registered: s.newDesc("fritzbox_telephony_number_registered", "Gauge showing whether the SIP account is registered", "line", "number", "status"),
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "fritzbox_telephony_calls_total",
+			Help:        "Counter of finished calls seen in the call list by the exporter",
+			ConstLabels: prometheus.Labels{"box": s.target.Name},
+		}, []string{"type"}),
+		callDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:        "fritzbox_telephony_call_duration_seconds",
+			Help:        "Histogram of the duration of answered calls seen in the call list by the exporter",
+			ConstLabels: prometheus.Labels{"box": s.target.Name},
+			Buckets:     callDurationBuckets,
+		}, []string{"type"}),
+	}
+	// export all series from the start, so increase() sees the first call
+	for _, t := range callTypes {
+		c.calls.WithLabelValues(t)
+	}
+	for _, t := range []string{"incoming", "outgoing"} {
+		c.callDuration.WithLabelValues(t)
+	}
+	return c
+}
+
+func (c *telephonyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.registered
+	c.calls.Describe(ch)
+	c.callDuration.Describe(ch)
+}
+
+func (c *telephonyCollector) Update(ch chan<- prometheus.Metric) error {
+	numbers, err := c.s.voipNumbers()
+	if err != nil {
+		return err
+	}
+	calls, err := c.s.callList()
+	if err != nil {
+		return err
+	}
+	c.count(calls)
+
+	// a number can be configured on several lines, but only once per line
+	exported := make(map[[2]string]bool, len(numbers))
+	for _, n := range numbers {
+		line := strconv.Itoa(n.Index)
+		if exported[[2]string{line, n.Number}] {
+			continue
+		}
+		exported[[2]string{line, n.Number}] = true
+		registered := 0.0
+		if n.Registered() {
+			registered = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.registered, prometheus.GaugeValue, registered, line, n.Number, n.Status)
+	}
+	c.calls.Collect(ch)
+	c.callDuration.Collect(ch)
+	return nil
+}
+
+// count adds the finished calls not seen in the previous call list. The
+// first list only marks its calls as seen.
+func (c *telephonyCollector) count(calls []fritz.Call) {
+	first := c.seen == nil
+	seen := make(map[int]bool, len(calls))
+	for _, call := range calls {
+		if call.Active() {
+			continue
+		}
+		seen[call.ID] = true
+		if first || c.seen[call.ID] {
+			continue
+		}
+		switch call.Type {
+		case "missed", "blocked":
+			c.calls.WithLabelValues(call.Type).Inc()
+		case "incoming", "outgoing":
+			c.calls.WithLabelValues(call.Type).Inc()
+			c.callDuration.WithLabelValues(call.Type).Observe(call.Duration.Seconds())
+		}
+	}
+	c.seen = seen
+}
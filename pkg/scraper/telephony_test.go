@@ -0,0 +1,42 @@
+package scraper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wbwue/FritzExporter/pkg/config"
+	"github.com/wbwue/FritzExporter/pkg/fritz"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestTelephonyCount(t *testing.T) {
+	c := newTelephonyCollector(&Scraper{target: config.Target{Name: "main"}})
+	c.count([]fritz.Call{
+		{ID: 1, Type: "incoming", Duration: 5 * time.Minute},
+		{ID: 2, Type: "missed"},
+	})
+	c.count([]fritz.Call{
+		{ID: 1, Type: "incoming", Duration: 5 * time.Minute},
+		{ID: 2, Type: "missed"},
+		{ID: 3, Type: "outgoing", Duration: 2 * time.Minute},
+		{ID: 4, Type: "missed"},
+		{ID: 5, Type: "active_incoming"},
+		{ID: 6, Type: "incoming", Duration: 90 * time.Minute},
+	})
+
+	for callType, want := range map[string]float64{"incoming": 1, "missed": 1, "outgoing": 1, "blocked": 0} {
+		if got := testutil.ToFloat64(c.calls.WithLabelValues(callType)); got != want {
+			t.Errorf("calls{type=%q} = %v, want %v", callType, got, want)
+		}
+	}
+	m := &dto.Metric{}
+	if err := c.callDuration.WithLabelValues("incoming").(prometheus.Metric).Write(m); err != nil {
+		t.Fatal(err)
+	}
+	if h := m.GetHistogram(); h.GetSampleCount() != 1 || h.GetSampleSum() != 5400 {
+		t.Errorf("incoming call durations: count %d, sum %v, want 1 and 5400", h.GetSampleCount(), h.GetSampleSum())
+	}
+}
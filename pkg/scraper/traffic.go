@@ -0,0 +1,80 @@
+package scraper
+
+import (
+	"github.com/wbwue/FritzExporter/pkg/fritz"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("traffic", true, func(s *Scraper) Collector { return newTrafficCollector(s) })
+}
+
+// trafficCollector exports the current internet traffic per traffic class
+// from the online monitor, for every sync group (WAN line) of the box.
+type trafficCollector struct {
+	s              *Scraper
+	downstream     *prometheus.Desc
+	upstream       *prometheus.Desc
+	downstreamMax  *prometheus.Desc
+	upstreamMax    *prometheus.Desc
+	downstreamLine *prometheus.Desc
+	upstreamLine   *prometheus.Desc
+	info           *prometheus.Desc
+}
+
+func newTrafficCollector(s *Scraper) *trafficCollector {
+	return &trafficCollector{
+		s:              s,
+		downstream:     s.newDesc("fritzbox_internet_downstream_current", "Gauge showing latest internet downstream speed", "sync_group", "type"),
+		upstream:       s.newDesc("fritzbox_internet_upstream_current", "Gauge showing latest internet upstream speed", "sync_group", "type"),
+		downstreamMax:  s.newDesc("fritzbox_internet_downstream_max", "Gauge showing the maximum internet downstream speed seen by the online monitor", "sync_group"),
+		upstreamMax:    s.newDesc("fritzbox_internet_upstream_max", "Gauge showing the maximum internet upstream speed seen by the online monitor", "sync_group"),
+		downstreamLine: s.newDesc("fritzbox_internet_downstream_line", "Gauge showing the downstream speed of the line as configured in the box", "sync_group"),
+		upstreamLine:   s.newDesc("fritzbox_internet_upstream_line", "Gauge showing the upstream speed of the line as configured in the box", "sync_group"),
+		info:           s.newDesc("fritzbox_internet_sync_group_info", "Gauge showing the sync groups of the online monitor", "sync_group", "mode"),
+	}
+}
+
+func (c *trafficCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.downstream
+	ch <- c.upstream
+	ch <- c.downstreamMax
+	ch <- c.upstreamMax
+	ch <- c.downstreamLine
+	ch <- c.upstreamLine
+	ch <- c.info
+}
+
+func (c *trafficCollector) Update(ch chan<- prometheus.Metric) error {
+	groups, err := c.s.trafficMonitoring()
+	if err != nil {
+		return err
+	}
+	for _, t := range groups {
+		c.rate(ch, c.downstream, t.DownstreamInternet, t.Name, "internet")
+		c.rate(ch, c.downstream, t.DownstreamMedia, t.Name, "media")
+		c.rate(ch, c.downstream, t.DownstreamGuest, t.Name, "guest")
+		c.rate(ch, c.upstream, t.UpstreamRealtime, t.Name, "realtime")
+		c.rate(ch, c.upstream, t.UpstreamHighPriority, t.Name, "high")
+		c.rate(ch, c.upstream, t.UpstreamDefaultPriority, t.Name, "default")
+		c.rate(ch, c.upstream, t.UpstreamLowPriority, t.Name, "low")
+		c.rate(ch, c.upstream, t.UpstreamGuest, t.Name, "guest")
+		ch <- prometheus.MustNewConstMetric(c.downstreamMax, prometheus.GaugeValue, t.DownstreamMax, t.Name)
+		ch <- prometheus.MustNewConstMetric(c.upstreamMax, prometheus.GaugeValue, t.UpstreamMax, t.Name)
+		ch <- prometheus.MustNewConstMetric(c.downstreamLine, prometheus.GaugeValue, t.Downstream, t.Name)
+		ch <- prometheus.MustNewConstMetric(c.upstreamLine, prometheus.GaugeValue, t.Upstream, t.Name)
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, t.Name, t.Mode)
+	}
+	return nil
+}
+
+// rate sends the current sample of a series, classes the box doesn't
+// report (e.g. guest without guest access) are left out.
+func (c *trafficCollector) rate(ch chan<- prometheus.Metric, desc *prometheus.Desc, series []float64, syncGroup string, class string) {
+	value, ok := fritz.Current(series)
+	if !ok {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, syncGroup, class)
+}
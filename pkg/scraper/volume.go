@@ -0,0 +1,54 @@
+package scraper
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("volume", true, func(s *Scraper) Collector { return newVolumeCollector(s) })
+}
+
+// volumeCollector exports the online counter of the box, which sums up the
+// traffic for today, yesterday, this week, this month and last month.
+type volumeCollector struct {
+	s           *Scraper
+	sent        *prometheus.Desc
+	received    *prometheus.Desc
+	total       *prometheus.Desc
+	onlineTime  *prometheus.Desc
+	connections *prometheus.Desc
+}
+
+func newVolumeCollector(s *Scraper) *volumeCollector {
+	return &volumeCollector{
+		s:           s,
+		sent:        s.newDesc("fritzbox_online_counter_sent_bytes", "Gauge showing the data volume sent in the period", "period"),
+		received:    s.newDesc("fritzbox_online_counter_received_bytes", "Gauge showing the data volume received in the period", "period"),
+		total:       s.newDesc("fritzbox_online_counter_total_bytes", "Gauge showing the total data volume of the period", "period"),
+		onlineTime:  s.newDesc("fritzbox_online_counter_online_seconds", "Gauge showing how long the box was online in the period", "period"),
+		connections: s.newDesc("fritzbox_online_counter_connections", "Gauge showing the number of internet connections in the period", "period"),
+	}
+}
+
+func (c *volumeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.sent
+	ch <- c.received
+	ch <- c.total
+	ch <- c.onlineTime
+	ch <- c.connections
+}
+
+func (c *volumeCollector) Update(ch chan<- prometheus.Metric) error {
+	counters, err := c.s.onlineCounter()
+	if err != nil {
+		return err
+	}
+	for _, v := range counters {
+		ch <- prometheus.MustNewConstMetric(c.sent, prometheus.GaugeValue, v.SentBytes, v.Period)
+		ch <- prometheus.MustNewConstMetric(c.received, prometheus.GaugeValue, v.ReceivedBytes, v.Period)
+		ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, v.TotalBytes, v.Period)
+		ch <- prometheus.MustNewConstMetric(c.onlineTime, prometheus.GaugeValue, v.OnlineTime.Seconds(), v.Period)
+		ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, v.Connections, v.Period)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package scraper
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/wbwue/FritzExporter/pkg/fritz"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+func init() {
+	registerCollector("wlan_radio", false, func(s *Scraper) Collector { return newWlanRadioCollector(s) })
+}
+
+// wlanRadioCollector exports the state of the WLAN radios and what the box
+// sees of the networks around it, to diagnose interference.
+type wlanRadioCollector struct {
+	s             *Scraper
+	enabled       *prometheus.Desc
+	channel       *prometheus.Desc
+	channelWidth  *prometheus.Desc
+	txPower       *prometheus.Desc
+	clients       *prometheus.Desc
+	utilization   *prometheus.Desc
+	neighbors     *prometheus.Desc
+	neighborsRSSI *prometheus.Desc
+}
+
+func newWlanRadioCollector(s *Scraper) *wlanRadioCollector {
+	labels := []string{"radio", "band", "ssid"}
+	return &wlanRadioCollector{
+		s:             s,
+		enabled:       s.newDesc("fritzbox_wlan_radio_enabled", "Gauge showing whether the WLAN is enabled", append(labels, "standard")...),
+		channel:       s.newDesc("fritzbox_wlan_radio_channel", "Gauge showing the channel the WLAN uses", labels...),
+		channelWidth:  s.newDesc("fritzbox_wlan_radio_channel_width_mhz", "Gauge showing the channel width the WLAN uses", labels...),
+		txPower:       s.newDesc("fritzbox_wlan_radio_tx_power_percent", "Gauge showing the transmit power of the WLAN", labels...),
+		clients:       s.newDesc("fritzbox_wlan_radio_associated_clients", "Gauge showing the number of clients associated with the WLAN", labels...),
+		utilization:   s.newDesc("fritzbox_wlan_channel_utilization_percent", "Gauge showing how busy the box measured the channel", "band", "channel"),
+		neighbors:     s.newDesc("fritzbox_wlan_neighbor_networks", "Gauge showing the number of foreign networks seen on the channel", "band", "channel"),
+		neighborsRSSI: s.newDesc("fritzbox_wlan_neighbor_rssi_max_dbm", "Gauge showing the signal of the strongest foreign network on the channel", "band", "channel"),
+	}
+}
+
+func (c *wlanRadioCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.enabled
+	ch <- c.channel
+	ch <- c.channelWidth
+	ch <- c.txPower
+	ch <- c.clients
+	ch <- c.utilization
+	ch <- c.neighbors
+	ch <- c.neighborsRSSI
+}
+
+func (c *wlanRadioCollector) Update(ch chan<- prometheus.Metric) error {
+	radios, err := c.s.wlanRadios()
+	if err != nil {
+		return err
+	}
+	for _, r := range radios {
+		labels := []string{strconv.Itoa(r.Index), r.Band, r.SSID}
+		enabled := 0.0
+		if r.Enabled {
+			enabled = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.enabled, prometheus.GaugeValue, enabled, append(labels, r.Standard)...)
+		ch <- prometheus.MustNewConstMetric(c.clients, prometheus.GaugeValue, r.Clients, labels...)
+		for desc, value := range map[*prometheus.Desc]*float64{c.channel: r.Channel, c.channelWidth: r.ChannelWidth, c.txPower: r.TxPower} {
+			if value != nil {
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, *value, labels...)
+			}
+		}
+	}
+
+	// the channel page needs the settings permission, the radios are
+	// exported without it and the update counts as successful
+	neighbors, usage, err := c.s.wlanChannels()
+	if errors.Is(err, fritz.ErrNotSupported) {
+		level.Debug(c.s.logger).Log("msg", "WLAN channel page not supported by box", "err", err)
+		return nil
+	}
+	if err != nil {
+		level.Warn(c.s.logger).Log("msg", "Failed to query WLAN channel page", "err", err)
+		return nil
+	}
+	for _, u := range usage {
+		ch <- prometheus.MustNewConstMetric(c.utilization, prometheus.GaugeValue, u.Utilization, u.Band, strconv.Itoa(u.Channel))
+	}
+	type bandChannel struct {
+		band    string
+		channel int
+	}
+	count := map[bandChannel]float64{}
+	strongest := map[bandChannel]float64{}
+	for _, n := range neighbors {
+		key := bandChannel{n.Band, n.Channel}
+		if rssi, ok := strongest[key]; !ok || n.RSSI > rssi {
+			strongest[key] = n.RSSI
+		}
+		count[key]++
+	}
+	for key, n := range count {
+		ch <- prometheus.MustNewConstMetric(c.neighbors, prometheus.GaugeValue, n, key.band, strconv.Itoa(key.channel))
+		ch <- prometheus.MustNewConstMetric(c.neighborsRSSI, prometheus.GaugeValue, strongest[key], key.band, strconv.Itoa(key.channel))
+	}
+	return nil
+}